@@ -6,6 +6,7 @@ import (
 )
 
 type Node interface {
+	Pos() token.Pos
 	String() string
 }
 
@@ -19,10 +20,28 @@ type Expr interface {
 	exprNode()
 }
 
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// TypeExpr is a type annotation.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
 type Program struct {
 	Stmts []Stmt
 }
 
+func (p *Program) Pos() token.Pos {
+	if len(p.Stmts) > 0 {
+		return p.Stmts[0].Pos()
+	}
+	return token.Pos{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -38,7 +57,8 @@ type ExprStmt struct {
 	Expr Expr
 }
 
-func (es *ExprStmt) stmtNode() {}
+func (es *ExprStmt) stmtNode()      {}
+func (es *ExprStmt) Pos() token.Pos { return es.Expr.Pos() }
 func (es *ExprStmt) String() string {
 	var out bytes.Buffer
 
@@ -48,17 +68,43 @@ func (es *ExprStmt) String() string {
 	return out.String()
 }
 
+// ImportStmt is import "path". Name is the identifier the module is
+// bound to, the last element of Path without its extension.
+type ImportStmt struct {
+	Import token.Pos
+	Path   *StringLit
+	Name   *Ident
+}
+
+func (is *ImportStmt) stmtNode()      {}
+func (is *ImportStmt) Pos() token.Pos { return is.Import }
+func (is *ImportStmt) String() string { return "import " + is.Path.String() + ";" }
+
+// LetStmt is let Pattern: Type = Value, or let mut Pattern: Type = Value
+// if Mut is set. Type is nil if the binding is not annotated. Pattern
+// binds names only: it never contains a *LitPattern.
 type LetStmt struct {
-	Name  *Ident
-	Value Expr
+	Let     token.Pos
+	Mut     bool
+	Pattern Pattern
+	Type    TypeExpr
+	Value   Expr
 }
 
-func (ls *LetStmt) stmtNode() {}
+func (ls *LetStmt) stmtNode()      {}
+func (ls *LetStmt) Pos() token.Pos { return ls.Let }
 func (ls *LetStmt) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("let ")
-	out.WriteString(ls.Name.String())
+	if ls.Mut {
+		out.WriteString("mut ")
+	}
+	out.WriteString(ls.Pattern.String())
+	if ls.Type != nil {
+		out.WriteString(": ")
+		out.WriteString(ls.Type.String())
+	}
 	if ls.Value != nil {
 		out.WriteString(" = ")
 		out.WriteString(ls.Value.String())
@@ -68,11 +114,66 @@ func (ls *LetStmt) String() string {
 	return out.String()
 }
 
-type ReturnStmt struct {
+// ConstStmt is const Name: Type = Value, where the type is optional.
+// Name cannot be assigned to.
+type ConstStmt struct {
+	Const token.Pos
+	Name  *Ident
+	Type  TypeExpr
 	Value Expr
 }
 
-func (rs *ReturnStmt) stmtNode() {}
+func (cs *ConstStmt) stmtNode()      {}
+func (cs *ConstStmt) Pos() token.Pos { return cs.Const }
+func (cs *ConstStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("const ")
+	out.WriteString(cs.Name.String())
+	if cs.Type != nil {
+		out.WriteString(": ")
+		out.WriteString(cs.Type.String())
+	}
+	out.WriteString(" = ")
+	out.WriteString(cs.Value.String())
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// StructStmt is struct Name { Fields }, which declares the struct type
+// Name.
+type StructStmt struct {
+	Struct token.Pos
+	Name   *Ident
+	Fields []*Field
+}
+
+func (ss *StructStmt) stmtNode()      {}
+func (ss *StructStmt) Pos() token.Pos { return ss.Struct }
+func (ss *StructStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("struct ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" {")
+	if len(ss.Fields) > 0 {
+		out.WriteString(" ")
+		writeList(&out, ss.Fields)
+		out.WriteString(" ")
+	}
+	out.WriteString("};")
+
+	return out.String()
+}
+
+type ReturnStmt struct {
+	Return token.Pos
+	Value  Expr
+}
+
+func (rs *ReturnStmt) stmtNode()      {}
+func (rs *ReturnStmt) Pos() token.Pos { return rs.Return }
 func (rs *ReturnStmt) String() string {
 	var out bytes.Buffer
 
@@ -86,16 +187,21 @@ func (rs *ReturnStmt) String() string {
 	return out.String()
 }
 
-type ContinueStmt struct{}
+type ContinueStmt struct {
+	Continue token.Pos
+}
 
 func (cs *ContinueStmt) stmtNode()      {}
+func (cs *ContinueStmt) Pos() token.Pos { return cs.Continue }
 func (cs *ContinueStmt) String() string { return "continue;" }
 
 type BreakStmt struct {
+	Break token.Pos
 	Value Expr
 }
 
-func (bs *BreakStmt) stmtNode() {}
+func (bs *BreakStmt) stmtNode()      {}
+func (bs *BreakStmt) Pos() token.Pos { return bs.Break }
 func (bs *BreakStmt) String() string {
 	var out bytes.Buffer
 
@@ -110,25 +216,63 @@ func (bs *BreakStmt) String() string {
 }
 
 type Ident struct {
-	Value string
+	NamePos token.Pos
+	Value   string
 }
 
 func (i *Ident) exprNode()      {}
+func (i *Ident) Pos() token.Pos { return i.NamePos }
 func (i *Ident) String() string { return i.Value }
 
 type IntLit struct {
-	Value string
+	ValuePos token.Pos
+	Value    string
 }
 
 func (il *IntLit) exprNode()      {}
+func (il *IntLit) Pos() token.Pos { return il.ValuePos }
 func (il *IntLit) String() string { return il.Value }
 
+// StringLit is a string literal. Value has its escapes decoded.
+type StringLit struct {
+	ValuePos token.Pos
+	Value    string
+}
+
+func (sl *StringLit) exprNode()      {}
+func (sl *StringLit) Pos() token.Pos { return sl.ValuePos }
+func (sl *StringLit) String() string { return token.Quote(sl.Value) }
+
+type BoolLit struct {
+	ValuePos token.Pos
+	Value    bool
+}
+
+func (bl *BoolLit) exprNode()      {}
+func (bl *BoolLit) Pos() token.Pos { return bl.ValuePos }
+func (bl *BoolLit) String() string {
+	if bl.Value {
+		return "true"
+	}
+	return "false"
+}
+
+type NullLit struct {
+	ValuePos token.Pos
+}
+
+func (nl *NullLit) exprNode()      {}
+func (nl *NullLit) Pos() token.Pos { return nl.ValuePos }
+func (nl *NullLit) String() string { return "null" }
+
 type PrefixExpr struct {
+	OpPos token.Pos
 	Op    token.Token
 	Right Expr
 }
 
-func (pe *PrefixExpr) exprNode() {}
+func (pe *PrefixExpr) exprNode()      {}
+func (pe *PrefixExpr) Pos() token.Pos { return pe.OpPos }
 func (pe *PrefixExpr) String() string {
 	var out bytes.Buffer
 
@@ -142,11 +286,13 @@ func (pe *PrefixExpr) String() string {
 
 type InfixExpr struct {
 	Left  Expr
+	OpPos token.Pos
 	Op    token.Token
 	Right Expr
 }
 
-func (ie *InfixExpr) exprNode() {}
+func (ie *InfixExpr) exprNode()      {}
+func (ie *InfixExpr) Pos() token.Pos { return ie.Left.Pos() }
 func (ie *InfixExpr) String() string {
 	var out bytes.Buffer
 
@@ -161,12 +307,105 @@ func (ie *InfixExpr) String() string {
 	return out.String()
 }
 
+// TupleLit is (a, b). A tuple of one element is written (a,).
+type TupleLit struct {
+	Lparen token.Pos
+	Elems  []Expr
+}
+
+func (tl *TupleLit) exprNode()      {}
+func (tl *TupleLit) Pos() token.Pos { return tl.Lparen }
+func (tl *TupleLit) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	writeList(&out, tl.Elems)
+	if len(tl.Elems) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+type ArrayLit struct {
+	Lbrack token.Pos
+	Elems  []Expr
+}
+
+func (al *ArrayLit) exprNode()      {}
+func (al *ArrayLit) Pos() token.Pos { return al.Lbrack }
+func (al *ArrayLit) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("[")
+	writeList(&out, al.Elems)
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// SelectorExpr is X.Sel.
+type SelectorExpr struct {
+	X   Expr
+	Dot token.Pos
+	Sel *Ident
+}
+
+func (se *SelectorExpr) exprNode()      {}
+func (se *SelectorExpr) Pos() token.Pos { return se.X.Pos() }
+func (se *SelectorExpr) String() string { return se.X.String() + "." + se.Sel.String() }
+
+// StructLit is Type { Fields }, which creates a struct of the struct
+// type Type. Type is a name or a selector. String parenthesizes the
+// literal, which then reparses in the clauses of if, while, for and
+// match as well.
+type StructLit struct {
+	Type   Expr
+	Lbrace token.Pos
+	Fields []*FieldValue
+}
+
+func (sl *StructLit) exprNode()      {}
+func (sl *StructLit) Pos() token.Pos { return sl.Type.Pos() }
+func (sl *StructLit) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(sl.Type.String())
+	out.WriteString(" {")
+	if len(sl.Fields) > 0 {
+		out.WriteString(" ")
+		writeList(&out, sl.Fields)
+		out.WriteString(" ")
+	}
+	out.WriteString("})")
+
+	return out.String()
+}
+
+// SpreadExpr is ...X in the arguments of a call, which passes the
+// elements of the array or tuple X as separate arguments.
+type SpreadExpr struct {
+	Ellipsis token.Pos
+	X        Expr
+}
+
+func (se *SpreadExpr) exprNode()      {}
+func (se *SpreadExpr) Pos() token.Pos { return se.Ellipsis }
+func (se *SpreadExpr) String() string { return "..." + se.X.String() }
+
+// CallExpr is Func(Args, Named). The positional arguments in Args come
+// before the named ones.
 type CallExpr struct {
-	Func Expr
-	Args []Expr
+	Func   Expr
+	Lparen token.Pos
+	Args   []Expr
+	Named  []*NamedArg
 }
 
-func (ce *CallExpr) exprNode() {}
+func (ce *CallExpr) exprNode()      {}
+func (ce *CallExpr) Pos() token.Pos { return ce.Func.Pos() }
 func (ce *CallExpr) String() string {
 	var out bytes.Buffer
 
@@ -176,16 +415,22 @@ func (ce *CallExpr) String() string {
 		out.WriteString(arg.String())
 		out.WriteString(", ")
 	}
+	for _, arg := range ce.Named {
+		out.WriteString(arg.String())
+		out.WriteString(", ")
+	}
 	out.WriteString(")")
 
 	return out.String()
 }
 
 type BlockExpr struct {
-	Stmts []Stmt
+	Lbrace token.Pos
+	Stmts  []Stmt
 }
 
-func (be *BlockExpr) exprNode() {}
+func (be *BlockExpr) exprNode()      {}
+func (be *BlockExpr) Pos() token.Pos { return be.Lbrace }
 func (be *BlockExpr) String() string {
 	var out bytes.Buffer
 
@@ -200,12 +445,14 @@ func (be *BlockExpr) String() string {
 }
 
 type IfExpr struct {
+	If        token.Pos
 	Condition Expr
 	TrueCase  Expr
 	FalseCase Expr
 }
 
-func (ie *IfExpr) exprNode() {}
+func (ie *IfExpr) exprNode()      {}
+func (ie *IfExpr) Pos() token.Pos { return ie.If }
 func (ie *IfExpr) String() string {
 	var out bytes.Buffer
 
@@ -222,11 +469,13 @@ func (ie *IfExpr) String() string {
 }
 
 type WhileExpr struct {
+	While     token.Pos
 	Condition Expr
 	Body      Expr
 }
 
-func (we *WhileExpr) exprNode() {}
+func (we *WhileExpr) exprNode()      {}
+func (we *WhileExpr) Pos() token.Pos { return we.While }
 func (we *WhileExpr) String() string {
 	var out bytes.Buffer
 
@@ -238,12 +487,199 @@ func (we *WhileExpr) String() string {
 	return out.String()
 }
 
+type ForExpr struct {
+	For  token.Pos
+	Var  *Ident
+	Iter Expr
+	Body Expr
+}
+
+func (fe *ForExpr) exprNode()      {}
+func (fe *ForExpr) Pos() token.Pos { return fe.For }
+func (fe *ForExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	out.WriteString(fe.Var.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iter.String())
+	out.WriteString(" ")
+	out.WriteString(fe.Body.String())
+
+	return out.String()
+}
+
+type MatchExpr struct {
+	Match   token.Pos
+	Subject Expr
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpr) exprNode()      {}
+func (me *MatchExpr) Pos() token.Pos { return me.Match }
+func (me *MatchExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	for _, arm := range me.Arms {
+		out.WriteString(arm.String())
+		out.WriteString(", ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// MatchArm is one pattern => body case of a match. Guard is nil when
+// the arm has no if clause.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expr
+	Arrow   token.Pos
+	Body    Expr
+}
+
+func (ma *MatchArm) Pos() token.Pos { return ma.Pattern.Pos() }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// WildcardPattern is _, which matches anything.
+type WildcardPattern struct {
+	Underscore token.Pos
+}
+
+func (wp *WildcardPattern) patternNode()   {}
+func (wp *WildcardPattern) Pos() token.Pos { return wp.Underscore }
+func (wp *WildcardPattern) String() string { return "_" }
+
+// BindPattern matches anything and binds it to Name.
+type BindPattern struct {
+	Name *Ident
+}
+
+func (bp *BindPattern) patternNode()   {}
+func (bp *BindPattern) Pos() token.Pos { return bp.Name.Pos() }
+func (bp *BindPattern) String() string { return bp.Name.String() }
+
+// LitPattern matches values equal to Value, an *IntLit, *StringLit,
+// *BoolLit or *NullLit. Negative ints are a single *IntLit with a leading "-".
+type LitPattern struct {
+	Value Expr
+}
+
+func (lp *LitPattern) patternNode()   {}
+func (lp *LitPattern) Pos() token.Pos { return lp.Value.Pos() }
+func (lp *LitPattern) String() string { return lp.Value.String() }
+
+// TuplePattern matches tuples with as many elements as Elems, each
+// matching its pattern.
+type TuplePattern struct {
+	Lparen token.Pos
+	Elems  []Pattern
+}
+
+func (tp *TuplePattern) patternNode()   {}
+func (tp *TuplePattern) Pos() token.Pos { return tp.Lparen }
+func (tp *TuplePattern) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	writeList(&out, tp.Elems)
+	if len(tp.Elems) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// ArrayPattern matches arrays whose leading elements match Elems. Without
+// Rest, the array must have exactly len(Elems) elements; with it, the
+// remaining elements are bound as a new array.
+type ArrayPattern struct {
+	Lbrack token.Pos
+	Elems  []Pattern
+	Rest   *RestPattern
+}
+
+func (ap *ArrayPattern) patternNode()   {}
+func (ap *ArrayPattern) Pos() token.Pos { return ap.Lbrack }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("[")
+	writeList(&out, ap.Elems)
+	if ap.Rest != nil {
+		if len(ap.Elems) > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(ap.Rest.String())
+	}
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// RestPattern is ...Name at the end of an array pattern. Name is nil for
+// a bare ..., which ignores the remaining elements.
+type RestPattern struct {
+	Ellipsis token.Pos
+	Name     *Ident
+}
+
+func (rp *RestPattern) Pos() token.Pos { return rp.Ellipsis }
+func (rp *RestPattern) String() string {
+	if rp.Name == nil {
+		return "..."
+	}
+	return "..." + rp.Name.String()
+}
+
+// PatternNames returns the names bound by pattern in source order.
+func PatternNames(pattern Pattern) []*Ident {
+	var names []*Ident
+	Inspect(pattern, func(n Node) bool {
+		switch n := n.(type) {
+		case *BindPattern:
+			names = append(names, n.Name)
+		case *RestPattern:
+			if n.Name != nil {
+				names = append(names, n.Name)
+			}
+		case *LitPattern:
+			return false
+		}
+		return true
+	})
+	return names
+}
+
+// FuncLit is func(Params, ...Rest) -> Result Body. Rest is nil unless
+// the function is variadic, and Result is nil if the result type is not
+// annotated.
 type FuncLit struct {
-	Params []*Ident
+	Func   token.Pos
+	Params []*Param
+	Rest   *RestPattern
+	Result TypeExpr
 	Body   Expr
 }
 
-func (fl *FuncLit) exprNode() {}
+func (fl *FuncLit) exprNode()      {}
+func (fl *FuncLit) Pos() token.Pos { return fl.Func }
 func (fl *FuncLit) String() string {
 	var out bytes.Buffer
 
@@ -252,8 +688,146 @@ func (fl *FuncLit) String() string {
 		out.WriteString(param.String())
 		out.WriteString(", ")
 	}
+	if fl.Rest != nil {
+		out.WriteString(fl.Rest.String())
+		out.WriteString(", ")
+	}
 	out.WriteString(") ")
+	if fl.Result != nil {
+		out.WriteString("-> ")
+		out.WriteString(fl.Result.String())
+		out.WriteString(" ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
+
+// NamedArg is Name: Value in the arguments of a call, which passes Value
+// to the parameter called Name.
+type NamedArg struct {
+	Name  *Ident
+	Colon token.Pos
+	Value Expr
+}
+
+func (na *NamedArg) Pos() token.Pos { return na.Name.Pos() }
+func (na *NamedArg) String() string { return na.Name.String() + ": " + na.Value.String() }
+
+// Field is a field of a struct declaration. Type is nil if the field is
+// not annotated.
+type Field struct {
+	Name *Ident
+	Type TypeExpr
+}
+
+func (f *Field) Pos() token.Pos { return f.Name.Pos() }
+func (f *Field) String() string {
+	if f.Type != nil {
+		return f.Name.String() + ": " + f.Type.String()
+	}
+	return f.Name.String()
+}
+
+// FieldValue is Name: Value in a struct literal.
+type FieldValue struct {
+	Name  *Ident
+	Colon token.Pos
+	Value Expr
+}
+
+func (fv *FieldValue) Pos() token.Pos { return fv.Name.Pos() }
+func (fv *FieldValue) String() string { return fv.Name.String() + ": " + fv.Value.String() }
+
+// Param is a function parameter. Type is nil if the parameter is not
+// annotated and Default is nil if it has no default value.
+type Param struct {
+	Pattern Pattern
+	Type    TypeExpr
+	Default Expr
+}
+
+func (p *Param) Pos() token.Pos { return p.Pattern.Pos() }
+func (p *Param) String() string {
+	s := p.Pattern.String()
+	if p.Type != nil {
+		s += ": " + p.Type.String()
+	}
+	if p.Default != nil {
+		s += " = " + p.Default.String()
+	}
+	return s
+}
+
+// NamedType is a predeclared type such as int or str, or the name of a
+// struct type.
+type NamedType struct {
+	Name *Ident
+}
+
+func (nt *NamedType) typeNode()      {}
+func (nt *NamedType) Pos() token.Pos { return nt.Name.Pos() }
+func (nt *NamedType) String() string { return nt.Name.String() }
+
+// ArrayType is [Elem], the type of arrays whose elements are all Elem.
+type ArrayType struct {
+	Lbrack token.Pos
+	Elem   TypeExpr
+}
+
+func (at *ArrayType) typeNode()      {}
+func (at *ArrayType) Pos() token.Pos { return at.Lbrack }
+func (at *ArrayType) String() string { return "[" + at.Elem.String() + "]" }
+
+// TupleType is (A, B), or (A,) for tuples of one element.
+type TupleType struct {
+	Lparen token.Pos
+	Elems  []TypeExpr
+}
+
+func (tt *TupleType) typeNode()      {}
+func (tt *TupleType) Pos() token.Pos { return tt.Lparen }
+func (tt *TupleType) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	writeList(&out, tt.Elems)
+	if len(tt.Elems) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// FuncType is func(Params) -> Result. Result is nil if it is omitted.
+type FuncType struct {
+	Func   token.Pos
+	Params []TypeExpr
+	Result TypeExpr
+}
+
+func (ft *FuncType) typeNode()      {}
+func (ft *FuncType) Pos() token.Pos { return ft.Func }
+func (ft *FuncType) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("func(")
+	writeList(&out, ft.Params)
+	out.WriteString(")")
+	if ft.Result != nil {
+		out.WriteString(" -> ")
+		out.WriteString(ft.Result.String())
+	}
+
+	return out.String()
+}
+
+func writeList[T Node](out *bytes.Buffer, nodes []T) {
+	for i, node := range nodes {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(node.String())
+	}
+}
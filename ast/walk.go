@@ -0,0 +1,170 @@
+package ast
+
+// Inspect traverses the tree rooted at node in depth-first order. It
+// calls f for each node; if f returns false, the children of that node
+// are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, stmt := range n.Stmts {
+			Inspect(stmt, f)
+		}
+	case *ExprStmt:
+		Inspect(n.Expr, f)
+	case *ImportStmt:
+		Inspect(n.Path, f)
+		Inspect(n.Name, f)
+	case *LetStmt:
+		Inspect(n.Pattern, f)
+		if n.Type != nil {
+			Inspect(n.Type, f)
+		}
+		Inspect(n.Value, f)
+	case *ConstStmt:
+		Inspect(n.Name, f)
+		if n.Type != nil {
+			Inspect(n.Type, f)
+		}
+		Inspect(n.Value, f)
+	case *StructStmt:
+		Inspect(n.Name, f)
+		for _, field := range n.Fields {
+			Inspect(field, f)
+		}
+	case *Field:
+		Inspect(n.Name, f)
+		if n.Type != nil {
+			Inspect(n.Type, f)
+		}
+	case *ReturnStmt:
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+	case *BreakStmt:
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+	case *PrefixExpr:
+		Inspect(n.Right, f)
+	case *InfixExpr:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *TupleLit:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+	case *ArrayLit:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+	case *SelectorExpr:
+		Inspect(n.X, f)
+		Inspect(n.Sel, f)
+	case *StructLit:
+		Inspect(n.Type, f)
+		for _, field := range n.Fields {
+			Inspect(field, f)
+		}
+	case *FieldValue:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *SpreadExpr:
+		Inspect(n.X, f)
+	case *CallExpr:
+		Inspect(n.Func, f)
+		for _, arg := range n.Args {
+			Inspect(arg, f)
+		}
+		for _, arg := range n.Named {
+			Inspect(arg, f)
+		}
+	case *NamedArg:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *BlockExpr:
+		for _, stmt := range n.Stmts {
+			Inspect(stmt, f)
+		}
+	case *IfExpr:
+		Inspect(n.Condition, f)
+		Inspect(n.TrueCase, f)
+		if n.FalseCase != nil {
+			Inspect(n.FalseCase, f)
+		}
+	case *WhileExpr:
+		Inspect(n.Condition, f)
+		Inspect(n.Body, f)
+	case *ForExpr:
+		Inspect(n.Var, f)
+		Inspect(n.Iter, f)
+		Inspect(n.Body, f)
+	case *MatchExpr:
+		Inspect(n.Subject, f)
+		for _, arm := range n.Arms {
+			Inspect(arm, f)
+		}
+	case *MatchArm:
+		Inspect(n.Pattern, f)
+		if n.Guard != nil {
+			Inspect(n.Guard, f)
+		}
+		Inspect(n.Body, f)
+	case *BindPattern:
+		Inspect(n.Name, f)
+	case *LitPattern:
+		Inspect(n.Value, f)
+	case *TuplePattern:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+	case *ArrayPattern:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+		if n.Rest != nil {
+			Inspect(n.Rest, f)
+		}
+	case *RestPattern:
+		if n.Name != nil {
+			Inspect(n.Name, f)
+		}
+	case *FuncLit:
+		for _, param := range n.Params {
+			Inspect(param, f)
+		}
+		if n.Rest != nil {
+			Inspect(n.Rest, f)
+		}
+		if n.Result != nil {
+			Inspect(n.Result, f)
+		}
+		Inspect(n.Body, f)
+	case *Param:
+		Inspect(n.Pattern, f)
+		if n.Type != nil {
+			Inspect(n.Type, f)
+		}
+		if n.Default != nil {
+			Inspect(n.Default, f)
+		}
+	case *NamedType:
+		Inspect(n.Name, f)
+	case *ArrayType:
+		Inspect(n.Elem, f)
+	case *TupleType:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+	case *FuncType:
+		for _, param := range n.Params {
+			Inspect(param, f)
+		}
+		if n.Result != nil {
+			Inspect(n.Result, f)
+		}
+	}
+}
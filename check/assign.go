@@ -0,0 +1,174 @@
+package check
+
+import (
+	"fmt"
+	"oasis/ast"
+	"oasis/diag"
+)
+
+// scope maps the names declared in a block to the statements declaring
+// them.
+type scope struct {
+	names map[string]ast.Node
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]ast.Node), outer: outer}
+}
+
+func (s *scope) lookup(name string) ast.Node {
+	for ; s != nil; s = s.outer {
+		if decl, ok := s.names[name]; ok {
+			return decl
+		}
+	}
+	return nil
+}
+
+// assignments reports assignments to constants and struct types and,
+// with Options.Immutable, to let bindings not declared mut. Names are
+// resolved the way the evaluator scopes them, except that function bodies
+// are checked after the rest of the program: by the time a function runs,
+// the scopes it closes over hold every name declared in them.
+type assignments struct {
+	opts  Options
+	funcs []deferredFunc
+	diags []*diag.Diagnostic
+}
+
+type deferredFunc struct {
+	lit   *ast.FuncLit
+	scope *scope
+}
+
+func checkAssignments(prog *ast.Program, opts Options) []*diag.Diagnostic {
+	a := &assignments{opts: opts}
+	a.walk(prog, newScope(nil))
+	for len(a.funcs) > 0 {
+		fn := a.funcs[0]
+		a.funcs = a.funcs[1:]
+
+		s := newScope(fn.scope)
+		for _, param := range fn.lit.Params {
+			for _, name := range ast.PatternNames(param.Pattern) {
+				s.names[name.Value] = name
+			}
+		}
+		if rest := fn.lit.Rest; rest != nil && rest.Name != nil {
+			s.names[rest.Name.Value] = rest.Name
+		}
+		for _, param := range fn.lit.Params {
+			if param.Default != nil {
+				a.walk(param.Default, s)
+			}
+		}
+		a.walk(fn.lit.Body, s)
+	}
+	return a.diags
+}
+
+func (a *assignments) walk(node ast.Node, s *scope) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ImportStmt:
+			s.names[n.Name.Value] = n
+			return false
+		case *ast.LetStmt:
+			a.walk(n.Value, s)
+			for _, name := range ast.PatternNames(n.Pattern) {
+				s.names[name.Value] = n
+			}
+			return false
+		case *ast.ConstStmt:
+			a.walk(n.Value, s)
+			s.names[n.Name.Value] = n
+			return false
+		case *ast.StructStmt:
+			s.names[n.Name.Value] = n
+			return false
+		case *ast.BlockExpr:
+			inner := newScope(s)
+			for _, stmt := range n.Stmts {
+				a.walk(stmt, inner)
+			}
+			return false
+		case *ast.ForExpr:
+			a.walk(n.Iter, s)
+			inner := newScope(s)
+			inner.names[n.Var.Value] = n.Var
+			a.walk(n.Body, inner)
+			return false
+		case *ast.MatchArm:
+			inner := newScope(s)
+			for _, name := range ast.PatternNames(n.Pattern) {
+				inner.names[name.Value] = name
+			}
+			if n.Guard != nil {
+				a.walk(n.Guard, inner)
+			}
+			a.walk(n.Body, inner)
+			return false
+		case *ast.FuncLit:
+			a.funcs = append(a.funcs, deferredFunc{lit: n, scope: s})
+			return false
+		case *ast.InfixExpr:
+			if name, ok := n.Left.(*ast.Ident); ok && n.Op.IsAssignment() {
+				a.assign(name, s.lookup(name.Value))
+			}
+		}
+		return true
+	})
+}
+
+func (a *assignments) assign(name *ast.Ident, decl ast.Node) {
+	var d *diag.Diagnostic
+	switch decl := decl.(type) {
+	case *ast.ConstStmt:
+		d = &diag.Diagnostic{
+			Msg: fmt.Sprintf("cannot assign to constant %s", name.Value),
+			Notes: []diag.Note{{
+				Pos: decl.Name.NamePos,
+				Len: diag.Span(decl.Name.Value),
+				Msg: fmt.Sprintf("%s is declared here", name.Value),
+			}},
+		}
+	case *ast.StructStmt:
+		d = &diag.Diagnostic{
+			Msg: fmt.Sprintf("cannot assign to struct type %s", name.Value),
+			Notes: []diag.Note{{
+				Pos: decl.Name.NamePos,
+				Len: diag.Span(decl.Name.Value),
+				Msg: fmt.Sprintf("%s is declared here", name.Value),
+			}},
+		}
+	case *ast.LetStmt:
+		if !a.opts.Immutable || decl.Mut {
+			return
+		}
+		// A plain binding is pointed out with its let, one in a pattern
+		// by its name alone.
+		note := diag.Note{Pos: decl.Let, Len: diag.Span("let " + name.Value)}
+		if _, ok := decl.Pattern.(*ast.BindPattern); !ok {
+			for _, bound := range ast.PatternNames(decl.Pattern) {
+				if bound.Value == name.Value {
+					note = diag.Note{Pos: bound.NamePos, Len: diag.Span(bound.Value)}
+				}
+			}
+		}
+		note.Msg = fmt.Sprintf("%s is declared here without mut", name.Value)
+
+		d = &diag.Diagnostic{
+			Msg:   fmt.Sprintf("cannot assign to immutable %s", name.Value),
+			Notes: []diag.Note{note},
+			Hints: []string{fmt.Sprintf("declare it with let mut %s to allow assignment", decl.Pattern)},
+		}
+	default:
+		return
+	}
+
+	d.Pos = name.NamePos
+	d.Len = diag.Span(name.Value)
+	d.Label = "assigned here"
+	a.diags = append(a.diags, d)
+}
@@ -0,0 +1,67 @@
+// Package check finds mistakes in programs that parse. Errors, such as
+// assignments to constants, must stop the program from running; warnings
+// point out code that is unlikely to do what was meant.
+package check
+
+import (
+	"oasis/ast"
+	"oasis/diag"
+	"sort"
+)
+
+// Options enable checks that not every program is written for.
+type Options struct {
+	// Immutable makes let bindings immutable: only those declared with
+	// let mut may be assigned to.
+	Immutable bool
+}
+
+// Program returns the errors and warnings for prog in source order.
+func Program(prog *ast.Program, opts Options) []*diag.Diagnostic {
+	diags := checkAssignments(prog, opts)
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if match, ok := node.(*ast.MatchExpr); ok {
+			diags = append(diags, unreachableArms(match)...)
+		}
+		return true
+	})
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+	return diags
+}
+
+// unreachableArms reports the arms of match that follow an arm matching
+// every value, which is a wildcard or binding pattern without a guard.
+func unreachableArms(match *ast.MatchExpr) []*diag.Diagnostic {
+	for i, arm := range match.Arms {
+		if arm.Guard != nil {
+			continue
+		}
+		switch arm.Pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindPattern:
+		default:
+			continue
+		}
+
+		var diags []*diag.Diagnostic
+		for _, next := range match.Arms[i+1:] {
+			diags = append(diags, &diag.Diagnostic{
+				Severity: diag.Warning,
+				Pos:      next.Pos(),
+				Len:      diag.Span(next.Pattern.String()),
+				Msg:      "unreachable match arm",
+				Label:    "never matched",
+				Notes: []diag.Note{{
+					Pos: arm.Pos(),
+					Len: diag.Span(arm.Pattern.String()),
+					Msg: "every value matches this arm",
+				}},
+			})
+		}
+		return diags
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package check
+
+import (
+	"oasis/diag"
+	"oasis/lexer"
+	"oasis/parser"
+	"strings"
+	"testing"
+)
+
+func TestUnreachableArms(t *testing.T) {
+	tests := []struct {
+		input string
+		warns []string
+	}{
+		{"match x { 1 => a, _ => b }", nil},
+		{"match x { n if n > 0 => a, _ => b }", nil},
+		{"match x { _ if c => a, 1 => b }", nil},
+		{"match x { _ => a, 1 => b }", []string{"1:19: unreachable match arm"}},
+		{"match x { 1 => a, n => b, _ => c, 2 => d }", []string{"1:27: unreachable match arm", "1:35: unreachable match arm"}},
+		{"let f = func(x) {\n\tmatch x {\n\t\t_ => 1\n\t\tn if n > 0 => 2\n\t}\n}", []string{"4:3: unreachable match arm"}},
+		{"match match y { _ => 1, 2 => 3 } { _ => a, _ => b }", []string{"1:25: unreachable match arm", "1:44: unreachable match arm"}},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		diags := Program(program, Options{})
+		if len(diags) != len(tt.warns) {
+			t.Fatalf("tests[%d]: expected %d warnings, got %d: %v", i, len(tt.warns), len(diags), diags)
+		}
+		for j, d := range diags {
+			if d.Error() != tt.warns[j] {
+				t.Fatalf("tests[%d]: expected %q, got %q", i, tt.warns[j], d)
+			}
+			if len(d.Notes) != 1 {
+				t.Fatalf("tests[%d]: expected a note pointing at the catch-all arm", i)
+			}
+		}
+	}
+}
+
+func TestConstAssignments(t *testing.T) {
+	tests := []struct {
+		input string
+		errs  []string
+	}{
+		{"const a = 1\nlet b = a + 1", nil},
+		{"const a = 1\na = 2", []string{"2:1: cannot assign to constant a"}},
+		{"const a = 1\na += 2\na <<= 1", []string{"2:1: cannot assign to constant a", "3:1: cannot assign to constant a"}},
+		{"const a = 1\n{ let a = 2\na = 3 }", nil},
+		{"const a = 1\nlet a = 2\na = 3", nil},
+		{"let a = 1\nconst a = 2\na = 3", []string{"3:1: cannot assign to constant a"}},
+		{"const a = 1\nlet f = func(a) { a = 2 }", nil},
+		{"const a = 1\nlet f = func() { a = 2 }", []string{"2:18: cannot assign to constant a"}},
+		{"let f = func() { n = 2 }\nconst n = 1", []string{"1:18: cannot assign to constant n"}},
+		{"const a = 1\nfor a in 0..3 { a = 2 }", nil},
+		{"const a = 1\nmatch 5 { a => { a = 2 } }", nil},
+		{"const a = 1\nmatch 5 { _ => { a = 2 } }", []string{"2:18: cannot assign to constant a"}},
+		{"let f = func() {\n\tconst limit = 10\n\tfunc() { limit -= 1 }\n}", []string{"3:11: cannot assign to constant limit"}},
+		{"struct P { x }\nP = 1", []string{"2:1: cannot assign to struct type P"}},
+		{"struct P { x }\nlet p = P { x: 1 }\np.x = 2", nil},
+		{"struct P { x }\n{ let P = 1\nP = 2 }", nil},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		diags := Program(program, Options{})
+		if len(diags) != len(tt.errs) {
+			t.Fatalf("tests[%d]: expected %d errors, got %d: %v", i, len(tt.errs), len(diags), diags)
+		}
+		for j, d := range diags {
+			if d.Error() != tt.errs[j] || d.Severity != diag.Error {
+				t.Fatalf("tests[%d]: expected error %q, got %s %q", i, tt.errs[j], d.Severity, d)
+			}
+			if len(d.Notes) != 1 {
+				t.Fatalf("tests[%d]: expected a note pointing at the declaration", i)
+			}
+		}
+	}
+}
+
+func TestImmutable(t *testing.T) {
+	tests := []struct {
+		input string
+		errs  []string
+	}{
+		{"let a = 1\nlet b = a + 1", nil},
+		{"let mut a = 1\na += 1", nil},
+		{"let a = 1\na = 2", []string{"2:1: cannot assign to immutable a"}},
+		{"let a = 1\nlet f = func() { a *= 2 }", []string{"2:18: cannot assign to immutable a"}},
+		{"let mut a = 1\n{ let a = 2\na = 3 }", []string{"3:1: cannot assign to immutable a"}},
+		{"let a = 1\nlet mut a = a\na = 3", nil},
+		{"let f = func(n) { n -= 1 }", nil},
+		{"for i in 0..3 { i += 1 }", nil},
+		{"const a = 1\na = 2", []string{"2:1: cannot assign to constant a"}},
+		{"let (a, [b, ...c]) = x\na = 1\nb = 2\nc = 3", []string{"2:1: cannot assign to immutable a", "3:1: cannot assign to immutable b", "4:1: cannot assign to immutable c"}},
+		{"let mut (a, b) = x\na = b", nil},
+		{"let f = func((a, b)) { a = b }", nil},
+		{"let a = 1\nmatch x { (a, _) => a = 2 }", nil},
+		{"let f = func(n, ...rest) { n = 1\nrest = [] }", nil},
+		{"let a = 1\nlet f = func(b = { a = 2 }) { b }", []string{"2:20: cannot assign to immutable a"}},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		diags := Program(program, Options{Immutable: true})
+		if len(diags) != len(tt.errs) {
+			t.Fatalf("tests[%d]: expected %d errors, got %d: %v", i, len(tt.errs), len(diags), diags)
+		}
+		for j, d := range diags {
+			if d.Error() != tt.errs[j] {
+				t.Fatalf("tests[%d]: expected error %q, got %q", i, tt.errs[j], d)
+			}
+		}
+
+		for _, d := range Program(program, Options{}) {
+			if strings.Contains(d.Msg, "immutable") {
+				t.Fatalf("tests[%d]: unexpected error without Immutable: %s", i, d)
+			}
+		}
+	}
+
+	program := parser.New(lexer.New("let mut x = 0\nlet total = x\ntotal = 1")).ParseProgram()
+	d := Program(program, Options{Immutable: true})[0]
+	if len(d.Notes) != 1 || d.Notes[0].Pos.Line != 2 || d.Notes[0].Pos.Col != 1 || d.Notes[0].Len != len("let total") {
+		t.Fatalf("expected a note pointing at the let statement, got %+v", d.Notes)
+	}
+	if len(d.Hints) != 1 || d.Hints[0] != "declare it with let mut total to allow assignment" {
+		t.Fatalf("wrong hints: %q", d.Hints)
+	}
+
+	program = parser.New(lexer.New("let [first, ...rest] = xs\nrest = []")).ParseProgram()
+	d = Program(program, Options{Immutable: true})[0]
+	if len(d.Notes) != 1 || d.Notes[0].Pos.Col != 16 || d.Notes[0].Len != len("rest") {
+		t.Fatalf("expected a note pointing at the name in the pattern, got %+v", d.Notes)
+	}
+	if len(d.Hints) != 1 || d.Hints[0] != "declare it with let mut [first, ...rest] to allow assignment" {
+		t.Fatalf("wrong hints: %q", d.Hints)
+	}
+}
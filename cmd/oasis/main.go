@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"oasis"
+	"oasis/diag"
+	"oasis/evaluator"
+	"oasis/loader"
+	"os"
+	"path/filepath"
+)
+
+// maxDepth turns runaway recursion into an Oasis error with a stack trace
+// instead of a Go stack overflow.
+const maxDepth = 10000
+
+func main() {
+	bigInts := flag.Bool("bigint", false, "use arbitrary-precision integers")
+	truthy := flag.Bool("truthy", false, "accept non-boolean conditions")
+	immutable := flag.Bool("immutable", false, "reject assignments to let bindings not declared mut")
+	path := flag.String("path", os.Getenv("OASISPATH"), "`dirs` searched for imports, separated by "+string(filepath.ListSeparator))
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "no input file specified")
+		os.Exit(1)
+	}
+	file := flag.Arg(0)
+
+	l := loader.New(searchPath(*path))
+	script, err := oasis.LoadOptions(l, file, oasis.Options{Immutable: *immutable, BigInts: *bigInts})
+
+	printer := diag.NewPrinter(os.Stderr)
+	for name, src := range l.Sources() {
+		printer.AddFile(name, src)
+	}
+
+	if err != nil {
+		report(printer, l.Sources(), file, err)
+		os.Exit(1)
+	}
+
+	for _, w := range script.Warnings() {
+		printer.Print(w.Pos.File, l.Sources()[w.Pos.File], w)
+	}
+
+	script.SetLimits(oasis.Limits{MaxDepth: maxDepth})
+	if *truthy {
+		script.SetTruthiness(oasis.Truthy)
+	}
+
+	if err := script.Run(context.Background()); err != nil {
+		report(printer, l.Sources(), file, err)
+		os.Exit(1)
+	}
+}
+
+// searchPath splits a list of directories such as $OASISPATH.
+func searchPath(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return filepath.SplitList(list)
+}
+
+func report(printer *diag.Printer, sources map[string]string, file string, err error) {
+	var d *diag.Diagnostic
+	var rerr *evaluator.Error
+	switch {
+	case errors.As(err, &d):
+	case errors.As(err, &rerr):
+		d = runtimeDiag(rerr)
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+		return
+	}
+	printer.Print(d.Pos.File, sources[d.Pos.File], d)
+}
+
+// maxFrames bounds how many stack frames are printed; deep recursion would
+// otherwise bury the error under thousands of identical snippets.
+const maxFrames = 10
+
+func runtimeDiag(rerr *evaluator.Error) *diag.Diagnostic {
+	d := &diag.Diagnostic{Pos: rerr.Pos, Len: 1, Msg: rerr.Err.Error()}
+	if rerr.Expr != "" {
+		d.Label = "in " + rerr.Expr
+	}
+
+	trace := rerr.Trace
+	if len(trace) > maxFrames {
+		trace = trace[:maxFrames]
+	}
+	for _, frame := range trace {
+		if frame.Call.IsValid() {
+			msg := frame.Func + " called here"
+			if frame.Tail {
+				msg = frame.Func + " tail called here"
+			}
+			d.Notes = append(d.Notes, diag.Note{Pos: frame.Call, Len: 1, Msg: msg})
+		} else {
+			d.Notes = append(d.Notes, diag.Note{Msg: frame.String()})
+		}
+	}
+	if n := len(rerr.Trace) - len(trace); n > 0 {
+		d.Notes = append(d.Notes, diag.Note{Msg: fmt.Sprintf("%d more frames omitted", n)})
+	}
+	return d
+}
@@ -0,0 +1,324 @@
+package oasis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"oasis/object"
+	"reflect"
+)
+
+var (
+	valueType  = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+)
+
+func (s *Script) toValue(v any) (object.Object, error) {
+	if v == nil {
+		return object.Null, nil
+	}
+	if obj, ok := v.(object.Object); ok {
+		return obj, nil
+	}
+	return s.reflectValue(reflect.ValueOf(v))
+}
+
+func (s *Script) reflectValue(rv reflect.Value) (object.Object, error) {
+	if rv.Type() == bigIntType && !rv.IsNil() {
+		return object.NewBigInt(new(big.Int).Set(rv.Interface().(*big.Int))), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return object.Null, nil
+	case reflect.Bool:
+		return object.NewBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Int{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows int", rv.Uint())
+		}
+		return &object.Int{Value: int64(rv.Uint())}, nil
+	case reflect.String:
+		return &object.String{Value: rv.String()}, nil
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return object.Null, nil
+		}
+		if rv.Type().Implements(valueType) {
+			return rv.Interface().(object.Object), nil
+		}
+		return s.reflectValue(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return object.Null, nil
+		}
+		elems := make([]object.Object, rv.Len())
+		for i := range elems {
+			elem, err := s.reflectValue(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elems[i] = elem
+		}
+		return &object.Array{Elems: elems}, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return object.Null, nil
+		}
+		m := object.NewMap()
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := s.reflectValue(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			hk, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable map key type %s", key.Type())
+			}
+			val, err := s.reflectValue(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key, err)
+			}
+			m.Set(hk, val)
+		}
+		return m, nil
+	case reflect.Func:
+		if rv.IsNil() {
+			return object.Null, nil
+		}
+		return s.wrapFunc(rv), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to an Oasis value", rv.Type())
+}
+
+// wrapFunc exposes a Go function as an Oasis builtin. Its results may be
+// empty, a single value, an error, or a value followed by an error.
+func (s *Script) wrapFunc(fn reflect.Value) *object.Builtin {
+	t := fn.Type()
+	n := t.NumIn()
+
+	arity := n
+	if t.IsVariadic() {
+		arity = object.Variadic
+	}
+
+	return &object.Builtin{
+		Name:  t.String(),
+		Arity: arity,
+		Fn: func(args []object.Object) (object.Object, error) {
+			if t.IsVariadic() && len(args) < n-1 {
+				return nil, fmt.Errorf("wrong number of arguments: want at least %d, got %d", n-1, len(args))
+			}
+
+			in := make([]reflect.Value, len(args))
+			for i, arg := range args {
+				var pt reflect.Type
+				if t.IsVariadic() && i >= n-1 {
+					pt = t.In(n - 1).Elem()
+				} else {
+					pt = t.In(i)
+				}
+
+				v, err := s.assignTo(arg, pt)
+				if err != nil {
+					return nil, &object.ArgError{Index: i, Err: err}
+				}
+				in[i] = v
+			}
+
+			out := fn.Call(in)
+			if len(out) > 0 && t.Out(len(out)-1) == errorType {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return nil, err
+				}
+				out = out[:len(out)-1]
+			}
+			if len(out) == 0 {
+				return object.Null, nil
+			}
+			return s.reflectValue(out[0])
+		},
+	}
+}
+
+// assignTo converts obj to a Go value of type t.
+func (s *Script) assignTo(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t.Implements(valueType) && reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	if t.Kind() == reflect.Interface {
+		v := s.fromValue(obj)
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.Type().AssignableTo(t) {
+			return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+		}
+		return rv, nil
+	}
+
+	mismatch := fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+
+	if t == bigIntType {
+		if i, ok := toBig(obj); ok {
+			return reflect.ValueOf(i), nil
+		}
+		return reflect.Value{}, mismatch
+	}
+	if i, ok := obj.(*object.BigInt); ok && t.Kind() >= reflect.Int && t.Kind() <= reflect.Uintptr {
+		return reflect.Value{}, fmt.Errorf("%s overflows %s", i, t)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, ok := obj.(*object.Bool); ok {
+			return reflect.ValueOf(b.Value).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*object.Int); ok {
+			v := reflect.New(t).Elem()
+			if v.OverflowInt(i.Value) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, t)
+			}
+			v.SetInt(i.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*object.Int); ok {
+			v := reflect.New(t).Elem()
+			if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, t)
+			}
+			v.SetUint(uint64(i.Value))
+			return v, nil
+		}
+	case reflect.String:
+		if str, ok := obj.(*object.String); ok {
+			return reflect.ValueOf(str.Value).Convert(t), nil
+		}
+	case reflect.Slice:
+		if obj == object.Null {
+			return reflect.Zero(t), nil
+		}
+		if arr, ok := obj.(*object.Array); ok {
+			v := reflect.MakeSlice(t, len(arr.Elems), len(arr.Elems))
+			for i, elem := range arr.Elems {
+				ev, err := s.assignTo(elem, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+				}
+				v.Index(i).Set(ev)
+			}
+			return v, nil
+		}
+	case reflect.Map:
+		if obj == object.Null {
+			return reflect.Zero(t), nil
+		}
+		if m, ok := obj.(*object.Map); ok {
+			v := reflect.MakeMapWithSize(t, m.Len())
+			for _, pair := range m.Pairs {
+				kv, err := s.assignTo(pair.Key, t.Key())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key, err)
+				}
+				vv, err := s.assignTo(pair.Value, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key, err)
+				}
+				v.SetMapIndex(kv, vv)
+			}
+			return v, nil
+		}
+	}
+
+	return reflect.Value{}, mismatch
+}
+
+// fromValue converts obj to a plain Go value.
+func (s *Script) fromValue(obj object.Object) any {
+	return s.export(obj, make(map[*object.Struct]map[string]any))
+}
+
+// export converts obj to a plain Go value. seen holds the maps the
+// structs converted so far became; a struct that occurs more than once
+// becomes the same map each time, so one that contains itself becomes a
+// map that contains itself.
+func (s *Script) export(obj object.Object, seen map[*object.Struct]map[string]any) any {
+	switch obj := obj.(type) {
+	case *object.NullValue:
+		return nil
+	case *object.Bool:
+		return obj.Value
+	case *object.Int:
+		return obj.Value
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value)
+	case *object.String:
+		return obj.Value
+	case *object.Array:
+		elems := make([]any, len(obj.Elems))
+		for i, elem := range obj.Elems {
+			elems[i] = s.export(elem, seen)
+		}
+		return elems
+	case *object.Tuple:
+		elems := make([]any, len(obj.Elems))
+		for i, elem := range obj.Elems {
+			elems[i] = s.export(elem, seen)
+		}
+		return elems
+	case *object.Map:
+		strs := make(map[string]any, obj.Len())
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				break
+			}
+			strs[key.Value] = s.export(pair.Value, seen)
+		}
+		if len(strs) == obj.Len() {
+			return strs
+		}
+
+		anys := make(map[any]any, obj.Len())
+		for _, pair := range obj.Pairs {
+			anys[s.export(pair.Key, seen)] = s.export(pair.Value, seen)
+		}
+		return anys
+	case *object.Struct:
+		if fields, ok := seen[obj]; ok {
+			return fields
+		}
+		fields := make(map[string]any, len(obj.Fields))
+		seen[obj] = fields
+		for i, val := range obj.Fields {
+			fields[obj.Def.Fields[i]] = s.export(val, seen)
+		}
+		return fields
+	case *object.Func, *object.Builtin:
+		return func(args ...any) (any, error) {
+			return s.call(context.Background(), obj, args)
+		}
+	}
+	return obj
+}
+
+// toBig returns a copy of the int obj as a *big.Int.
+func toBig(obj object.Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return big.NewInt(obj.Value), true
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value), true
+	}
+	return nil, false
+}
@@ -0,0 +1,202 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"oasis/token"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Note is additional information attached to a diagnostic. If Pos is
+// valid the note is rendered with its own source snippet.
+type Note struct {
+	Pos token.Pos
+	Len int
+	Msg string
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Pos      token.Pos
+	Len      int
+	Msg      string
+	Label    string
+	Notes    []Note
+	Hints    []string
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+const (
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	amber = "\x1b[1;33m"
+	blue  = "\x1b[1;34m"
+	green = "\x1b[1;32m"
+)
+
+type Printer struct {
+	w     io.Writer
+	color bool
+	files map[string]string
+}
+
+// NewPrinter returns a printer writing to w, using ANSI colors if w is a
+// terminal and NO_COLOR is not set.
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w, color: IsTerminal(w) && os.Getenv("NO_COLOR") == ""}
+}
+
+func (p *Printer) SetColor(color bool) {
+	p.color = color
+}
+
+// AddFile makes the contents of file available for the snippets of
+// positions in other files than the one being printed.
+func (p *Printer) AddFile(file, src string) {
+	if p.files == nil {
+		p.files = make(map[string]string)
+	}
+	p.files[file] = src
+}
+
+// Print renders d rustc-style against src, the contents of file.
+func (p *Printer) Print(file, src string, d *Diagnostic) error {
+	var out strings.Builder
+	lines := strings.Split(src, "\n")
+
+	width := len(fmt.Sprint(d.Pos.Line))
+	for _, note := range d.Notes {
+		if n := len(fmt.Sprint(note.Pos.Line)); n > width {
+			width = n
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+
+	color := red
+	if d.Severity == Warning {
+		color = amber
+	}
+
+	out.WriteString(p.paint(color, d.Severity.String()))
+	out.WriteString(p.paint(bold, ": "+d.Msg))
+	out.WriteString("\n")
+	p.snippet(&out, file, lines, gutter, color, d.Pos, d.Len, d.Label)
+
+	for _, note := range d.Notes {
+		if !note.Pos.IsValid() {
+			fmt.Fprintf(&out, "%s %s %s: %s\n", gutter, p.paint(blue, "="), p.paint(bold, "note"), note.Msg)
+			continue
+		}
+		fmt.Fprintf(&out, "%s: %s\n", p.paint(bold, "note"), note.Msg)
+		p.snippet(&out, file, lines, gutter, blue, note.Pos, note.Len, "")
+	}
+
+	for _, hint := range d.Hints {
+		fmt.Fprintf(&out, "%s %s %s: %s\n", gutter, p.paint(blue, "="), p.paint(green, "help"), hint)
+	}
+
+	_, err := io.WriteString(p.w, out.String())
+	return err
+}
+
+func (p *Printer) snippet(out *strings.Builder, file string, lines []string, gutter, color string, pos token.Pos, n int, label string) {
+	if pos.File != "" && pos.File != file {
+		file, lines = pos.File, nil
+		if src, ok := p.files[file]; ok {
+			lines = strings.Split(src, "\n")
+		}
+	}
+
+	if !pos.IsValid() {
+		fmt.Fprintf(out, "%s%s %s\n", gutter, p.paint(blue, "-->"), file)
+		return
+	}
+	fmt.Fprintf(out, "%s%s %s:%d:%d\n", gutter, p.paint(blue, "-->"), file, pos.Line, pos.Col)
+
+	if pos.Line > len(lines) {
+		return
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+
+	bar := p.paint(blue, "|")
+	fmt.Fprintf(out, "%s %s\n", gutter, bar)
+	fmt.Fprintf(out, "%s %s %s\n", p.paint(blue, fmt.Sprintf("%*d", len(gutter), pos.Line)), bar, expandTabs(line))
+
+	if n < 1 {
+		n = 1
+	}
+	var pad strings.Builder
+	col := 1
+	for _, ch := range line {
+		if col >= pos.Col {
+			break
+		}
+		if ch == '\t' {
+			pad.WriteString("    ")
+		} else {
+			pad.WriteByte(' ')
+		}
+		col++
+	}
+	for ; col < pos.Col; col++ {
+		pad.WriteByte(' ')
+	}
+
+	marker := p.paint(color, strings.Repeat("^", n))
+	if label != "" {
+		marker += " " + p.paint(color, label)
+	}
+	fmt.Fprintf(out, "%s %s %s%s\n", gutter, bar, pad.String(), marker)
+}
+
+func (p *Printer) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + reset
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+// Span returns the length in runes of lit for use as a diagnostic span.
+func Span(lit string) int {
+	if n := utf8.RuneCountInString(lit); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// IsTerminal reports whether w is a character device such as a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
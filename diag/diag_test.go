@@ -0,0 +1,131 @@
+package diag
+
+import (
+	"bytes"
+	"oasis/token"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	src := "let a = 10\nlet b = {\n\ta + }\n"
+
+	tests := []struct {
+		diag   *Diagnostic
+		output string
+	}{
+		{
+			&Diagnostic{Pos: token.Pos{Line: 3, Col: 6}, Len: 1, Msg: `expected expression, got "}"`},
+			`error: expected expression, got "}"
+ --> main.oasis:3:6
+  |
+3 |     a + }
+  |         ^
+`,
+		},
+		{
+			&Diagnostic{
+				Severity: Warning,
+				Pos:      token.Pos{Line: 1, Col: 5},
+				Len:      1,
+				Msg:      "unused variable",
+				Label:    "never read",
+				Notes:    []Note{{Msg: "variables are block scoped"}},
+				Hints:    []string{"remove the binding"},
+			},
+			`warning: unused variable
+ --> main.oasis:1:5
+  |
+1 | let a = 10
+  |     ^ never read
+  = note: variables are block scoped
+  = help: remove the binding
+`,
+		},
+		{
+			&Diagnostic{
+				Pos:   token.Pos{Line: 3, Col: 2},
+				Len:   5,
+				Msg:   "cannot assign",
+				Notes: []Note{{Pos: token.Pos{Line: 1, Col: 5}, Len: 1, Msg: "declared here"}},
+			},
+			`error: cannot assign
+ --> main.oasis:3:2
+  |
+3 |     a + }
+  |     ^^^^^
+note: declared here
+ --> main.oasis:1:5
+  |
+1 | let a = 10
+  |     ^
+`,
+		},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		if err := NewPrinter(&out).Print("main.oasis", src, tt.diag); err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+
+		if out.String() != tt.output {
+			t.Fatalf("tests[%d]: expected\n%s\ngot\n%s", i, tt.output, out.String())
+		}
+	}
+}
+
+func TestPrintColor(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPrinter(&out)
+	p.SetColor(true)
+
+	d := &Diagnostic{Pos: token.Pos{Line: 1, Col: 1}, Len: 3, Msg: "bad"}
+	if err := p.Print("x.oasis", "let", d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\x1b[1;31merror\x1b[0m\x1b[1m: bad\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m x.oasis:1:1\n" +
+		"  \x1b[1;34m|\x1b[0m\n" +
+		"\x1b[1;34m1\x1b[0m \x1b[1;34m|\x1b[0m let\n" +
+		"  \x1b[1;34m|\x1b[0m \x1b[1;31m^^^\x1b[0m\n"
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestPrintFiles(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPrinter(&out)
+	p.AddFile("lib/util.oasis", "let Half = func(x) {\n\tx / 0\n}")
+
+	d := &Diagnostic{
+		Pos: token.Pos{File: "lib/util.oasis", Line: 2, Col: 4},
+		Len: 1,
+		Msg: "integer division by zero",
+		Notes: []Note{
+			{Pos: token.Pos{File: "main.oasis", Line: 2, Col: 1}, Len: 1, Msg: "Half called here"},
+			{Pos: token.Pos{File: "gone.oasis", Line: 7, Col: 3}, Len: 1, Msg: "f called here"},
+		},
+	}
+	if err := p.Print("main.oasis", "import \"lib/util\"\nutil.Half(1)", d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `error: integer division by zero
+ --> lib/util.oasis:2:4
+  |
+2 |     x / 0
+  |       ^
+note: Half called here
+ --> main.oasis:2:1
+  |
+2 | util.Half(1)
+  | ^
+note: f called here
+ --> gone.oasis:7:3
+`
+	if out.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,106 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"oasis/object"
+	"oasis/token"
+)
+
+// maxBigShift bounds the shift count of << on big ints, which would
+// otherwise let a single expression allocate without limit.
+const maxBigShift = 1 << 24
+
+func toBig(obj object.Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return big.NewInt(obj.Value), true
+	case *object.BigInt:
+		return obj.Value, true
+	}
+	return nil, false
+}
+
+// overflows reports whether a op b wraps around in int64 arithmetic.
+func overflows(op token.Token, a, b int64) bool {
+	switch op {
+	case token.ADD:
+		c := a + b
+		return (c > a) != (b > 0)
+	case token.SUB:
+		c := a - b
+		return (c < a) != (b > 0)
+	case token.MUL:
+		if a == 0 || b == 0 {
+			return false
+		}
+		c := a * b
+		return c/b != a || a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64
+	case token.DIV:
+		return a == math.MinInt64 && b == -1
+	case token.LSHIFT:
+		return b > 0 && (b >= 64 || a<<b>>b != a)
+	}
+	return false
+}
+
+// evalBigInfixExpr is evalIntInfixExpr for ints of any size. The results
+// are the same wherever int64 arithmetic does not overflow.
+func evalBigInfixExpr(pos token.Pos, op token.Token, a, b *big.Int) (object.Object, error) {
+	z := new(big.Int)
+
+	switch op {
+	case token.ADD:
+		return object.NewBigInt(z.Add(a, b)), nil
+	case token.SUB:
+		return object.NewBigInt(z.Sub(a, b)), nil
+	case token.MUL:
+		return object.NewBigInt(z.Mul(a, b)), nil
+	case token.DIV, token.MOD:
+		if b.Sign() == 0 {
+			return nil, errorf(pos, ErrDivisionByZero, "integer division by zero")
+		}
+		if op == token.DIV {
+			return object.NewBigInt(z.Quo(a, b)), nil
+		}
+		return object.NewBigInt(z.Rem(a, b)), nil
+	case token.AND:
+		return object.NewBigInt(z.And(a, b)), nil
+	case token.OR:
+		return object.NewBigInt(z.Or(a, b)), nil
+	case token.XOR:
+		return object.NewBigInt(z.Xor(a, b)), nil
+	case token.LSHIFT, token.RSHIFT:
+		if b.Sign() < 0 {
+			return nil, errorf(pos, ErrType, "negative shift count %s", b)
+		}
+		if op == token.RSHIFT {
+			if !b.IsUint64() || b.Uint64() > uint64(a.BitLen()) {
+				if a.Sign() < 0 {
+					return &object.Int{Value: -1}, nil
+				}
+				return &object.Int{Value: 0}, nil
+			}
+			return object.NewBigInt(z.Rsh(a, uint(b.Uint64()))), nil
+		}
+		if a.Sign() == 0 {
+			return &object.Int{Value: 0}, nil
+		}
+		if !b.IsInt64() || b.Int64() > maxBigShift {
+			return nil, errorf(pos, ErrType, "shift count %s too large", b)
+		}
+		return object.NewBigInt(z.Lsh(a, uint(b.Int64()))), nil
+	case token.DOTDOT, token.DOTDOT_EQ:
+		return nil, errorf(pos, ErrType, "range bounds must fit in 64 bits")
+	case token.LT:
+		return object.NewBool(a.Cmp(b) < 0), nil
+	case token.LTE:
+		return object.NewBool(a.Cmp(b) <= 0), nil
+	case token.GT:
+		return object.NewBool(a.Cmp(b) > 0), nil
+	case token.GTE:
+		return object.NewBool(a.Cmp(b) >= 0), nil
+	}
+
+	return nil, errorf(pos, ErrType, "invalid operation: operator %s not defined on int", op)
+}
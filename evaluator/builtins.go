@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"fmt"
+	"oasis/lexer"
+	"oasis/object"
+	"oasis/token"
+	"sort"
+	"strings"
+)
+
+// Registry holds the builtins visible to a program. Builtins are resolved
+// like any identifier, after every scope of the program has been searched.
+type Registry struct {
+	builtins map[string]*object.Builtin
+}
+
+func NewRegistry() *Registry {
+	return &Registry{builtins: make(map[string]*object.Builtin)}
+}
+
+// Register adds a builtin called name that takes arity arguments, or any
+// number if arity is object.Variadic.
+func (r *Registry) Register(name string, arity int, doc string, fn object.BuiltinFunc) error {
+	l := lexer.New(name)
+	if tok, lit := l.NextToken(); tok != token.IDENT || lit != name {
+		return fmt.Errorf("invalid builtin name %q", name)
+	}
+	if arity < object.Variadic {
+		return fmt.Errorf("invalid arity %d for builtin %s", arity, name)
+	}
+	if _, ok := r.builtins[name]; ok {
+		return fmt.Errorf("builtin %s already registered", name)
+	}
+
+	r.builtins[name] = &object.Builtin{Name: name, Arity: arity, Doc: doc, Fn: fn}
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*object.Builtin, bool) {
+	b, ok := r.builtins[name]
+	return b, ok
+}
+
+// Names returns the names of all registered builtins in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.builtins))
+	for name := range r.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (in *Interpreter) registerDefaults() {
+	in.builtins.Register("len", 1, "len(x) returns the number of elements in a str, array or map.", builtinLen)
+	in.builtins.Register("type", 1, "type(x) returns the name of the type of x.", builtinType)
+	in.builtins.Register("str", 1, "str(x) returns x formatted as a str.", builtinStr)
+	in.builtins.Register("help", 1, "help(f) returns the documentation of the builtin f.", builtinHelp)
+	in.builtins.Register("print", object.Variadic, "print(args...) writes its arguments separated by spaces and a newline.", in.builtinPrint)
+}
+
+func builtinLen(args []object.Object) (object.Object, error) {
+	switch arg := args[0].(type) {
+	case *object.String:
+		return &object.Int{Value: int64(len(arg.Value))}, nil
+	case *object.Array:
+		return &object.Int{Value: int64(len(arg.Elems))}, nil
+	case *object.Map:
+		return &object.Int{Value: int64(arg.Len())}, nil
+	}
+	return nil, object.ArgTypeError(0, "str, array or map", args[0])
+}
+
+func builtinType(args []object.Object) (object.Object, error) {
+	return &object.String{Value: args[0].Type().String()}, nil
+}
+
+func builtinStr(args []object.Object) (object.Object, error) {
+	return &object.String{Value: args[0].String()}, nil
+}
+
+func builtinHelp(args []object.Object) (object.Object, error) {
+	b, ok := args[0].(*object.Builtin)
+	if !ok {
+		return nil, object.ArgTypeError(0, "builtin", args[0])
+	}
+	return &object.String{Value: b.Doc}, nil
+}
+
+func (in *Interpreter) builtinPrint(args []object.Object) (object.Object, error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = arg.String()
+	}
+	_, err := fmt.Fprintln(in.Stdout, strings.Join(strs, " "))
+	return object.Null, err
+}
@@ -0,0 +1,892 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"oasis/ast"
+	"oasis/object"
+	"oasis/token"
+	"os"
+	"strconv"
+)
+
+// Error is a runtime error raised while evaluating a program.
+type Error struct {
+	Pos token.Pos
+	Err error
+	// Expr is the source of the expression that failed, if known.
+	Expr string
+	// Trace holds the calls active when the error was raised, innermost
+	// first. It is empty for errors outside any function.
+	Trace []Frame
+}
+
+// Frame is a function call in a stack trace.
+type Frame struct {
+	Func string
+	// Call is the position of the call, invalid for calls from the host.
+	Call token.Pos
+	// Tail reports that the call was a tail call, which replaced the
+	// frames of the functions that made it.
+	Tail bool
+}
+
+func (f Frame) String() string {
+	if !f.Call.IsValid() {
+		return f.Func + " called from host"
+	}
+	if f.Tail {
+		return fmt.Sprintf("%s tail called at %s", f.Func, f.Call)
+	}
+	return fmt.Sprintf("%s called at %s", f.Func, f.Call)
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var (
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrNotCallable    = errors.New("value is not callable")
+	ErrArgCount       = errors.New("wrong number of arguments")
+	ErrUndefined      = errors.New("undefined")
+	ErrType           = errors.New("type error")
+	ErrPattern        = errors.New("pattern mismatch")
+	ErrArgName        = errors.New("bad argument name")
+	ErrField          = errors.New("bad field")
+	ErrConst          = errors.New("assignment to constant")
+)
+
+// Control flow is threaded through the error return so that it unwinds
+// nested blocks and loops without every caller having to inspect it.
+type returnSignal struct {
+	pos   token.Pos
+	value object.Object
+}
+
+func (s *returnSignal) Error() string { return "return outside function" }
+
+type breakSignal struct {
+	pos   token.Pos
+	value object.Object
+}
+
+func (s *breakSignal) Error() string { return "break outside loop" }
+
+type continueSignal struct {
+	pos token.Pos
+}
+
+func (s *continueSignal) Error() string { return "continue outside loop" }
+
+type Interpreter struct {
+	// Stdout receives the output of the print builtin.
+	Stdout io.Writer
+	Limits Limits
+	// BigInts makes ints arbitrary precision. Arithmetic that would
+	// overflow an int64 switches to math/big instead of wrapping around.
+	BigInts bool
+	// Truthiness decides which values conditions accept.
+	Truthiness Truthiness
+	// Importer resolves import statements. Without one, imports fail.
+	Importer Importer
+
+	ctx      context.Context
+	builtins *Registry
+
+	analyzed  map[*ast.FuncLit]bool
+	tailCalls map[*ast.CallExpr]bool
+	modules   map[string]*object.Module
+
+	active    int
+	steps     int64
+	depth     int
+	allocated int64
+	frames    []Frame
+}
+
+func New() *Interpreter {
+	in := &Interpreter{
+		Stdout:    os.Stdout,
+		ctx:       context.Background(),
+		builtins:  NewRegistry(),
+		analyzed:  make(map[*ast.FuncLit]bool),
+		tailCalls: make(map[*ast.CallExpr]bool),
+		modules:   make(map[string]*object.Module),
+	}
+	in.registerDefaults()
+	return in
+}
+
+func (in *Interpreter) Builtins() *Registry {
+	return in.builtins
+}
+
+// Run evaluates program in env and returns the value of its last
+// statement, or the value of a top-level return.
+func (in *Interpreter) Run(ctx context.Context, program *ast.Program, env *object.Env) (object.Object, error) {
+	if err := in.enter(ctx); err != nil {
+		return nil, err
+	}
+	defer in.leave()
+
+	var result object.Object = object.Null
+	for _, stmt := range program.Stmts {
+		val, err := in.eval(stmt, env)
+		if err != nil {
+			var ret *returnSignal
+			if errors.As(err, &ret) {
+				return ret.value, nil
+			}
+			return nil, unhandled(err)
+		}
+		result = val
+	}
+
+	return result, nil
+}
+
+// Call applies fn, which must be a function or builtin, to args.
+func (in *Interpreter) Call(ctx context.Context, fn object.Object, args []object.Object) (object.Object, error) {
+	if err := in.enter(ctx); err != nil {
+		return nil, err
+	}
+	defer in.leave()
+
+	return in.apply(nil, fn, args)
+}
+
+func (in *Interpreter) eval(node ast.Node, env *object.Env) (object.Object, error) {
+	if err := in.step(node); err != nil {
+		return nil, err
+	}
+
+	val, err := in.evalNode(node, env)
+	if err != nil {
+		switch node.(type) {
+		case *ast.BlockExpr, *ast.IfExpr, *ast.WhileExpr, *ast.ForExpr, *ast.MatchExpr, *ast.FuncLit:
+		case ast.Expr:
+			if rerr, ok := err.(*Error); ok && rerr.Expr == "" {
+				rerr.Expr = node.String()
+			}
+		}
+	}
+	return val, err
+}
+
+func (in *Interpreter) evalNode(node ast.Node, env *object.Env) (object.Object, error) {
+	switch node := node.(type) {
+	case *ast.ExprStmt:
+		return in.eval(node.Expr, env)
+
+	case *ast.ImportStmt:
+		return in.evalImportStmt(node, env)
+
+	case *ast.LetStmt:
+		return in.evalBinding(node.Pattern, node.Value, env)
+
+	case *ast.ConstStmt:
+		return in.evalConstStmt(node, env)
+
+	case *ast.StructStmt:
+		return in.evalStructStmt(node, env)
+
+	case *ast.ReturnStmt:
+		var val object.Object = object.Null
+		if node.Value != nil {
+			var err error
+			if val, err = in.eval(node.Value, env); err != nil {
+				return nil, err
+			}
+		}
+		return nil, &returnSignal{pos: node.Return, value: val}
+
+	case *ast.BreakStmt:
+		var val object.Object = object.Null
+		if node.Value != nil {
+			var err error
+			if val, err = in.eval(node.Value, env); err != nil {
+				return nil, err
+			}
+		}
+		return nil, &breakSignal{pos: node.Break, value: val}
+
+	case *ast.ContinueStmt:
+		return nil, &continueSignal{pos: node.Continue}
+
+	case *ast.Ident:
+		if val, ok := env.Get(node.Value); ok {
+			return val, nil
+		}
+		if b, ok := in.builtins.Lookup(node.Value); ok {
+			return b, nil
+		}
+		return nil, errorf(node.NamePos, ErrUndefined, "undefined: %s", node.Value)
+
+	case *ast.IntLit:
+		v, err := strconv.ParseInt(node.Value, 10, 64)
+		if err != nil && in.BigInts {
+			if v, ok := new(big.Int).SetString(node.Value, 10); ok {
+				return object.NewBigInt(v), nil
+			}
+		}
+		if err != nil {
+			return nil, errorf(node.ValuePos, ErrType, "integer literal %s out of range", node.Value)
+		}
+		return &object.Int{Value: v}, nil
+
+	case *ast.StringLit:
+		return &object.String{Value: node.Value}, nil
+
+	case *ast.BoolLit:
+		return object.NewBool(node.Value), nil
+
+	case *ast.NullLit:
+		return object.Null, nil
+
+	case *ast.PrefixExpr:
+		// -9223372036854775808 is the only way to spell the smallest int,
+		// whose magnitude alone does not fit in an int64.
+		if lit, ok := node.Right.(*ast.IntLit); ok && node.Op == token.SUB {
+			if v, err := strconv.ParseInt("-"+lit.Value, 10, 64); err == nil {
+				return &object.Int{Value: v}, nil
+			}
+		}
+
+		right, err := in.eval(node.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		return in.evalPrefixExpr(node, right)
+
+	case *ast.InfixExpr:
+		if node.Op.IsAssignment() {
+			return in.evalAssign(node, env)
+		}
+		if node.Op == token.LAND || node.Op == token.LOR {
+			return in.evalLogicalExpr(node, env)
+		}
+
+		left, err := in.eval(node.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		right, err := in.eval(node.Right, env)
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := in.evalInfixExpr(node.OpPos, node.Op, left, right)
+		if err != nil {
+			return nil, err
+		}
+		return val, in.track(node.OpPos, val)
+
+	case *ast.TupleLit:
+		elems, err := in.evalExprs(node.Elems, env)
+		if err != nil {
+			return nil, err
+		}
+		val := &object.Tuple{Elems: elems}
+		return val, in.track(node.Lparen, val)
+
+	case *ast.ArrayLit:
+		elems, err := in.evalExprs(node.Elems, env)
+		if err != nil {
+			return nil, err
+		}
+		val := &object.Array{Elems: elems}
+		return val, in.track(node.Lbrack, val)
+
+	case *ast.SelectorExpr:
+		return in.evalSelectorExpr(node, env)
+
+	case *ast.StructLit:
+		return in.evalStructLit(node, env)
+
+	case *ast.CallExpr:
+		fn, err := in.eval(node.Func, env)
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := in.evalArgs(node.Args, env)
+		if err != nil {
+			return nil, err
+		}
+		if len(node.Named) > 0 {
+			if args, err = in.evalNamedArgs(node, fn, args, env); err != nil {
+				return nil, err
+			}
+		}
+
+		if fn, ok := fn.(*object.Func); ok && in.tailCalls[node] && checkArity(node.Pos(), fn, len(args)) == nil {
+			return nil, &tailCall{call: node, fn: fn, args: args}
+		}
+		return in.apply(node, fn, args)
+
+	case *ast.BlockExpr:
+		if err := in.alloc(node.Lbrace, envSize); err != nil {
+			return nil, err
+		}
+		return in.evalBlock(node.Stmts, object.NewEnv(env))
+
+	case *ast.IfExpr:
+		return in.evalIfExpr(node, env)
+
+	case *ast.WhileExpr:
+		return in.evalWhileExpr(node, env)
+
+	case *ast.ForExpr:
+		return in.evalForExpr(node, env)
+
+	case *ast.MatchExpr:
+		return in.evalMatchExpr(node, env)
+
+	case *ast.FuncLit:
+		if err := in.alloc(node.Func, funcSize); err != nil {
+			return nil, err
+		}
+		return &object.Func{Lit: node, Env: env}, nil
+	}
+
+	return nil, errorf(node.Pos(), ErrType, "cannot evaluate %T", node)
+}
+
+// evalBinding binds the names in pattern to the value of expr. A function
+// bound to a plain name while still unnamed takes the name, for use in
+// stack traces.
+func (in *Interpreter) evalBinding(pattern ast.Pattern, expr ast.Expr, env *object.Env) (object.Object, error) {
+	val, err := in.eval(expr, env)
+	if err != nil {
+		return nil, err
+	}
+	if bind, ok := pattern.(*ast.BindPattern); ok {
+		if fn, ok := val.(*object.Func); ok && fn.Name == "" {
+			fn.Name = bind.Name.Value
+		}
+	}
+	return object.Null, in.bind(pattern, val, env)
+}
+
+// evalConstStmt binds a constant. The checks in package check reject
+// assignments to constants before the program runs, but a closure may
+// run while its name still denotes a constant it was not resolved to.
+func (in *Interpreter) evalConstStmt(node *ast.ConstStmt, env *object.Env) (object.Object, error) {
+	if _, err := in.evalBinding(&ast.BindPattern{Name: node.Name}, node.Value, env); err != nil {
+		return nil, err
+	}
+	val, _ := env.Get(node.Name.Value)
+	env.DefineConst(node.Name.Value, val)
+	return object.Null, nil
+}
+
+func (in *Interpreter) evalExprs(exprs []ast.Expr, env *object.Env) ([]object.Object, error) {
+	vals := make([]object.Object, len(exprs))
+	for i, expr := range exprs {
+		var err error
+		if vals[i], err = in.eval(expr, env); err != nil {
+			return nil, err
+		}
+	}
+	return vals, nil
+}
+
+// evalBlock returns the value of the last statement if it is an
+// expression statement, and null otherwise.
+func (in *Interpreter) evalBlock(stmts []ast.Stmt, env *object.Env) (object.Object, error) {
+	var result object.Object = object.Null
+	for _, stmt := range stmts {
+		val, err := in.eval(stmt, env)
+		if err != nil {
+			return nil, err
+		}
+		result = val
+	}
+	return result, nil
+}
+
+func (in *Interpreter) evalIfExpr(node *ast.IfExpr, env *object.Env) (object.Object, error) {
+	cond, err := in.evalCondition(node.Condition, env, "condition")
+	if err != nil {
+		return nil, err
+	}
+
+	if cond {
+		return in.eval(node.TrueCase, env)
+	}
+	if node.FalseCase != nil {
+		return in.eval(node.FalseCase, env)
+	}
+	return object.Null, nil
+}
+
+// evalWhileExpr returns the value of the break that ended the loop, or
+// null if the condition did.
+func (in *Interpreter) evalWhileExpr(node *ast.WhileExpr, env *object.Env) (object.Object, error) {
+	for {
+		cond, err := in.evalCondition(node.Condition, env, "condition")
+		if err != nil {
+			return nil, err
+		}
+		if !cond {
+			return object.Null, nil
+		}
+
+		if _, err := in.eval(node.Body, env); err != nil {
+			var brk *breakSignal
+			var cont *continueSignal
+			switch {
+			case errors.As(err, &brk):
+				return brk.value, nil
+			case errors.As(err, &cont):
+				continue
+			default:
+				return nil, err
+			}
+		}
+	}
+}
+
+// evalForExpr runs the body once for each int of a range, element of an
+// array or key of a map, binding it to the loop variable in a new scope.
+// Like a while loop, it returns the value of the break that ended it, or
+// null.
+func (in *Interpreter) evalForExpr(node *ast.ForExpr, env *object.Env) (object.Object, error) {
+	iter, err := in.eval(node.Iter, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var result object.Object = object.Null
+	body := func(val object.Object) (bool, error) {
+		if err := in.alloc(node.For, envSize); err != nil {
+			return false, err
+		}
+		scope := object.NewEnv(env)
+		scope.Define(node.Var.Value, val)
+
+		if _, err := in.eval(node.Body, scope); err != nil {
+			var brk *breakSignal
+			var cont *continueSignal
+			switch {
+			case errors.As(err, &brk):
+				result = brk.value
+				return false, nil
+			case errors.As(err, &cont):
+				return true, nil
+			default:
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	switch iter := iter.(type) {
+	case *object.Range:
+		for i := iter.Start; i < iter.End || iter.Inclusive && i == iter.End; i++ {
+			more, err := body(&object.Int{Value: i})
+			if !more || err != nil || i == math.MaxInt64 {
+				return result, err
+			}
+		}
+	case *object.Array:
+		for _, elem := range iter.Elems {
+			if more, err := body(elem); !more || err != nil {
+				return result, err
+			}
+		}
+	case *object.Map:
+		for _, pair := range iter.Pairs {
+			if more, err := body(pair.Key); !more || err != nil {
+				return result, err
+			}
+		}
+	default:
+		err := errorf(node.Iter.Pos(), ErrType, "cannot iterate over %s (type %s)", node.Iter, iter.Type())
+		err.Expr = node.Iter.String()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// evalMatchExpr evaluates the body of the first arm whose pattern matches
+// the subject and whose guard, if any, holds. Each arm has its own scope
+// for the names its pattern binds. A match without a matching arm
+// evaluates to null, like an if without else.
+func (in *Interpreter) evalMatchExpr(node *ast.MatchExpr, env *object.Env) (object.Object, error) {
+	subject, err := in.eval(node.Subject, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range node.Arms {
+		if err := in.alloc(arm.Pos(), envSize); err != nil {
+			return nil, err
+		}
+		scope := object.NewEnv(env)
+
+		ok, err := in.matchPattern(arm.Pattern, subject, scope)
+		if err != nil {
+			return nil, err
+		}
+		if ok && arm.Guard != nil {
+			if ok, err = in.evalCondition(arm.Guard, scope, "match guard"); err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			return in.eval(arm.Body, scope)
+		}
+	}
+
+	return object.Null, nil
+}
+
+// matchPattern reports whether val matches pattern, binding the names in
+// pattern in env. Names may be bound even if a later part of the pattern
+// fails to match.
+func (in *Interpreter) matchPattern(pattern ast.Pattern, val object.Object, env *object.Env) (bool, error) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+	case *ast.BindPattern:
+		env.Define(pattern.Name.Value, val)
+		return true, nil
+	case *ast.LitPattern:
+		lit, err := in.eval(pattern.Value, env)
+		if err != nil {
+			return false, err
+		}
+		return object.Equal(lit, val), nil
+	case *ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+		if !ok || len(tuple.Elems) != len(pattern.Elems) {
+			return false, nil
+		}
+		return in.matchElems(pattern.Elems, tuple.Elems, env)
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok || len(arr.Elems) < len(pattern.Elems) || pattern.Rest == nil && len(arr.Elems) > len(pattern.Elems) {
+			return false, nil
+		}
+		if ok, err := in.matchElems(pattern.Elems, arr.Elems, env); !ok || err != nil {
+			return ok, err
+		}
+		if pattern.Rest != nil && pattern.Rest.Name != nil {
+			rest := &object.Array{Elems: append([]object.Object(nil), arr.Elems[len(pattern.Elems):]...)}
+			if err := in.track(pattern.Rest.Ellipsis, rest); err != nil {
+				return false, err
+			}
+			env.Define(pattern.Rest.Name.Value, rest)
+		}
+		return true, nil
+	}
+
+	return false, errorf(pattern.Pos(), ErrType, "cannot match %T", pattern)
+}
+
+func (in *Interpreter) matchElems(patterns []ast.Pattern, vals []object.Object, env *object.Env) (bool, error) {
+	for i, pattern := range patterns {
+		if ok, err := in.matchPattern(pattern, vals[i], env); !ok || err != nil {
+			return ok, err
+		}
+	}
+	return true, nil
+}
+
+// bind binds the names in pattern, a pattern without literals, to the
+// parts of val. A value of the wrong shape is an error at the innermost
+// pattern it does not fit.
+func (in *Interpreter) bind(pattern ast.Pattern, val object.Object, env *object.Env) error {
+	ok, err := in.matchPattern(pattern, val, env)
+	if err != nil || ok {
+		return err
+	}
+	if err := mismatch(pattern, val); err != nil {
+		return err
+	}
+	return errorf(pattern.Pos(), ErrPattern, "%s does not match pattern %s", val, pattern)
+}
+
+// mismatch returns the error for a value that does not fit the shape of
+// pattern, or nil if it does.
+func mismatch(pattern ast.Pattern, val object.Object) *Error {
+	var patterns []ast.Pattern
+	var elems []object.Object
+	switch pattern := pattern.(type) {
+	case *ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+		if !ok {
+			return errorf(pattern.Pos(), ErrPattern, "cannot destructure %s (type %s) with tuple pattern %s", val, val.Type(), pattern)
+		}
+		if len(tuple.Elems) != len(pattern.Elems) {
+			return errorf(pattern.Pos(), ErrPattern, "tuple pattern %s needs %d elements, got %d", pattern, len(pattern.Elems), len(tuple.Elems))
+		}
+		patterns, elems = pattern.Elems, tuple.Elems
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return errorf(pattern.Pos(), ErrPattern, "cannot destructure %s (type %s) with array pattern %s", val, val.Type(), pattern)
+		}
+		if pattern.Rest != nil && len(arr.Elems) < len(pattern.Elems) {
+			return errorf(pattern.Pos(), ErrPattern, "array pattern %s needs at least %d elements, got %d", pattern, len(pattern.Elems), len(arr.Elems))
+		}
+		if pattern.Rest == nil && len(arr.Elems) != len(pattern.Elems) {
+			return errorf(pattern.Pos(), ErrPattern, "array pattern %s needs %d elements, got %d", pattern, len(pattern.Elems), len(arr.Elems))
+		}
+		patterns, elems = pattern.Elems, arr.Elems
+	}
+
+	for i, elem := range patterns {
+		if err := mismatch(elem, elems[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evalCondition evaluates expr as a boolean. use describes the role of
+// expr for the error reported in strict mode.
+func (in *Interpreter) evalCondition(expr ast.Expr, env *object.Env, use string) (bool, error) {
+	val, err := in.eval(expr, env)
+	if err != nil {
+		return false, err
+	}
+
+	if in.Truthiness == Truthy {
+		return truthy(val), nil
+	}
+
+	b, ok := val.(*object.Bool)
+	if !ok {
+		err := errorf(expr.Pos(), ErrType, "non-bool %s (type %s) used as %s", expr, val.Type(), use)
+		err.Expr = expr.String()
+		return false, err
+	}
+	return b.Value, nil
+}
+
+func (in *Interpreter) evalLogicalExpr(node *ast.InfixExpr, env *object.Env) (object.Object, error) {
+	if in.Truthiness == Truthy {
+		left, err := in.eval(node.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		if node.Op == token.LAND && !truthy(left) || node.Op == token.LOR && truthy(left) {
+			return left, nil
+		}
+		return in.eval(node.Right, env)
+	}
+
+	left, err := in.evalCondition(node.Left, env, "operand of "+node.Op.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Op == token.LAND && !left || node.Op == token.LOR && left {
+		return object.NewBool(left), nil
+	}
+
+	right, err := in.evalCondition(node.Right, env, "operand of "+node.Op.String())
+	if err != nil {
+		return nil, err
+	}
+	return object.NewBool(right), nil
+}
+
+var assignOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN:    token.ADD,
+	token.SUB_ASSIGN:    token.SUB,
+	token.MUL_ASSIGN:    token.MUL,
+	token.DIV_ASSIGN:    token.DIV,
+	token.MOD_ASSIGN:    token.MOD,
+	token.AND_ASSIGN:    token.AND,
+	token.OR_ASSIGN:     token.OR,
+	token.XOR_ASSIGN:    token.XOR,
+	token.LSHIFT_ASSIGN: token.LSHIFT,
+	token.RSHIFT_ASSIGN: token.RSHIFT,
+}
+
+func (in *Interpreter) evalAssign(node *ast.InfixExpr, env *object.Env) (object.Object, error) {
+	if sel, ok := node.Left.(*ast.SelectorExpr); ok {
+		return in.evalFieldAssign(node, sel, env)
+	}
+	ident, ok := node.Left.(*ast.Ident)
+	if !ok {
+		return nil, errorf(node.OpPos, ErrType, "cannot assign to %s", node.Left)
+	}
+	if env.IsConst(ident.Value) {
+		return nil, errorf(ident.NamePos, ErrConst, "cannot assign to constant %s", ident.Value)
+	}
+
+	val, err := in.eval(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := assignOps[node.Op]; ok {
+		cur, ok := env.Get(ident.Value)
+		if !ok {
+			return nil, errorf(ident.NamePos, ErrUndefined, "undefined: %s", ident.Value)
+		}
+		if val, err = in.evalInfixExpr(node.OpPos, op, cur, val); err != nil {
+			return nil, err
+		}
+		if err := in.track(node.OpPos, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if !env.Assign(ident.Value, val) {
+		return nil, errorf(ident.NamePos, ErrUndefined, "undefined: %s", ident.Value)
+	}
+	return val, nil
+}
+
+// apply calls fn with args. call is the call site, or nil when the call
+// comes from the host.
+func (in *Interpreter) apply(call *ast.CallExpr, fn object.Object, args []object.Object) (object.Object, error) {
+	var pos token.Pos
+	if call != nil {
+		pos = call.Pos()
+	}
+
+	switch fn := fn.(type) {
+	case *object.Func:
+		if err := checkArity(pos, fn, len(args)); err != nil {
+			return nil, err
+		}
+
+		in.frames = append(in.frames, Frame{Func: funcName(fn), Call: pos})
+		defer in.popFrame()
+
+		in.depth++
+		defer func() { in.depth-- }()
+		if in.Limits.MaxDepth > 0 && in.depth > in.Limits.MaxDepth {
+			return nil, errorf(pos, ErrDepthLimit, "maximum call depth of %d exceeded", in.Limits.MaxDepth)
+		}
+
+		// Tail calls replace fn and its frame and go around again.
+		for {
+			if err := in.alloc(pos, envSize); err != nil {
+				return nil, err
+			}
+			in.markTailCalls(fn.Lit)
+
+			env := object.NewEnv(fn.Env)
+			if err := in.bindParams(pos, fn, args, env); err != nil {
+				return nil, in.traced(unhandled(err))
+			}
+
+			val, err := in.eval(fn.Lit.Body, env)
+			if err != nil {
+				var ret *returnSignal
+				var tail *tailCall
+				switch {
+				case errors.As(err, &ret):
+					return ret.value, nil
+				case errors.As(err, &tail):
+					fn, args, pos = tail.fn, tail.args, tail.call.Pos()
+					in.frames[len(in.frames)-1] = Frame{Func: funcName(fn), Call: pos, Tail: true}
+					continue
+				}
+				return nil, in.traced(unhandled(err))
+			}
+			return val, nil
+		}
+
+	case *object.Builtin:
+		if fn.Arity != object.Variadic && len(args) != fn.Arity {
+			return nil, errorf(pos, ErrArgCount, "wrong number of arguments to %s: want %d, got %d", fn.Name, fn.Arity, len(args))
+		}
+
+		in.frames = append(in.frames, Frame{Func: fn.Name, Call: pos})
+		defer in.popFrame()
+
+		val, err := fn.Fn(args)
+		if err != nil {
+			var rerr *Error
+			if errors.As(err, &rerr) {
+				return nil, err
+			}
+
+			var aerr *object.ArgError
+			if errors.As(err, &aerr) && call != nil {
+				pos = argPos(call, aerr.Index)
+			}
+			return nil, &Error{Pos: pos, Err: fmt.Errorf("%s: %w", fn.Name, err), Trace: in.stackTrace()[1:]}
+		}
+		if val == nil {
+			val = object.Null
+		}
+		return val, in.track(pos, val)
+	}
+
+	return nil, errorf(pos, ErrNotCallable, "cannot call %s value %s", fn.Type(), fn)
+}
+
+func (in *Interpreter) popFrame() {
+	in.frames = in.frames[:len(in.frames)-1]
+}
+
+// stackTrace returns a copy of the active frames, innermost first.
+func (in *Interpreter) stackTrace() []Frame {
+	trace := make([]Frame, len(in.frames))
+	for i, frame := range in.frames {
+		trace[len(trace)-1-i] = frame
+	}
+	return trace
+}
+
+// traced attaches the current stack to err if it is a runtime error
+// that has not been given one yet.
+func (in *Interpreter) traced(err error) error {
+	if rerr, ok := err.(*Error); ok && rerr.Trace == nil {
+		rerr.Trace = in.stackTrace()
+	}
+	return err
+}
+
+func funcName(fn *object.Func) string {
+	if fn.Name != "" {
+		return fn.Name
+	}
+	return fmt.Sprintf("func literal at %s", fn.Lit.Func)
+}
+
+// unhandled turns break and continue signals that escaped their loop
+// into positioned errors.
+func unhandled(err error) error {
+	switch sig := err.(type) {
+	case *breakSignal:
+		return &Error{Pos: sig.pos, Err: errors.New(sig.Error())}
+	case *continueSignal:
+		return &Error{Pos: sig.pos, Err: errors.New(sig.Error())}
+	case *returnSignal:
+		return &Error{Pos: sig.pos, Err: errors.New(sig.Error())}
+	}
+	return err
+}
+
+// errorf returns a runtime error at pos that matches kind with errors.Is.
+func errorf(pos token.Pos, kind error, format string, args ...any) *Error {
+	return &Error{Pos: pos, Err: &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}}
+}
+
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
@@ -0,0 +1,1164 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"oasis/lexer"
+	"oasis/object"
+	"oasis/parser"
+	"oasis/token"
+	"testing"
+	"time"
+)
+
+func run(t *testing.T, input string) (object.Object, error) {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if program == nil {
+		t.Fatalf("%q: %s", input, p.Error())
+	}
+
+	return New().Run(context.Background(), program, object.NewEnv(nil))
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"10", "10"},
+		{"-10", "-10"},
+		{"~0", "-1"},
+		{"!true", "false"},
+		{"null", "null"},
+		{"1 + 2 * 3 - 4 / 2", "5"},
+		{"7 % 3", "1"},
+		{"const k = 6\nk * 7", "42"},
+		{"const a = 1\nlet a = 2\na = 3\na", "3"},
+		{"const a = 1\nlet f = func() { a = 2 }\nlet a = 0\nf()\na", "2"},
+		{`"a" + "b\tc"`, "ab\tc"},
+		{`"\"" == "\""`, "true"},
+		{"6 & 3 | 8 ^ 1", "11"},
+		{"1 << 4 >> 2", "4"},
+		{"1 < 2 && 2 <= 2 && 3 > 2 && 3 >= 3", "true"},
+		{"1 == 1 && 1 != 2 && null == null && true != false", "true"},
+		{"1 == true", "false"},
+		{"false && undefined", "false"},
+		{"true || undefined", "true"},
+		{"let a = 10\na", "10"},
+		{"let a = 1\na += 2\na *= 3\na", "9"},
+		{"let a = 1\n{ let a = 2 }\na", "1"},
+		{"let a = 1\n{ a = 2 }\na", "2"},
+		{"if 1 < 2 { 10 } else { 20 }", "10"},
+		{"if 1 > 2 { 10 } else if false { 20 } else { 30 }", "30"},
+		{"if false { 10 }", "null"},
+		{"let i = 0\nwhile i < 10 { i += 1 }\ni", "10"},
+		{"let i = 0\nwhile true { i += 1\nif i == 5 { break } }\ni", "5"},
+		{"let i = 0\nlet n = 0\nwhile i < 10 { i += 1\nif i % 2 == 0 { continue }\nn += 1 }\nn", "5"},
+		{"let add = func(a, b) { a + b }\nadd(1, 2)", "3"},
+		{"let f = func(n) { if n == 0 { return 42 }\nreturn f(n - 1) }\nf(10)", "42"},
+		{"let fib = func(n) { if n < 2 { return n }\nfib(n - 1) + fib(n - 2) }\nfib(15)", "610"},
+		{"func(x) { x * 2 }(21)", "42"},
+		{"return 5\n10", "5"},
+	}
+
+	for i, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+
+		if result.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, result.String())
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+		kind  error
+	}{
+		{"a", "1:1: undefined: a", ErrUndefined},
+		{"a = 1", "1:1: undefined: a", ErrUndefined},
+		{"1 / 0", "1:3: integer division by zero", ErrDivisionByZero},
+		{"let a = 1\na /= 0", "2:3: integer division by zero", ErrDivisionByZero},
+		{"1 << -1", "1:3: negative shift count -1", ErrType},
+		{"1 + true", "1:3: invalid operation: operator + not defined on int and bool", ErrType},
+		{"-false", "1:1: invalid operation: -false (operator - not defined on bool)", ErrType},
+		{"if 1 { 2 }", "1:4: non-bool 1 (type int) used as condition", ErrType},
+		{"1 && true", "1:1: non-bool 1 (type int) used as operand of &&", ErrType},
+		{"99999999999999999999", "1:1: integer literal 99999999999999999999 out of range", ErrType},
+		{"let a = 1\na(2)", "2:1: cannot call int value 1", ErrNotCallable},
+		{"func(a) { a }(1, 2)", "1:1: wrong number of arguments to func literal at 1:1: want 1, got 2", ErrArgCount},
+		{"1 = 2", "1:3: cannot assign to 1", ErrType},
+		{"break", "1:1: break outside loop", nil},
+		{"while true { func() { continue }() }", "1:23: continue outside loop", nil},
+		{`import "m"`, `1:8: cannot import "m": no importer configured`, ErrImport},
+		{"let x = 1\nx.y", "2:2: x (type int) has no member y", ErrType},
+		{"const k = 1\nk += 2", "2:1: cannot assign to constant k", ErrConst},
+		{"const a = 1\nlet f = func() { a = 2 }\nf()\nlet a = 3", "2:18: cannot assign to constant a", ErrConst},
+	}
+
+	for i, tt := range tests {
+		_, err := run(t, tt.input)
+		if err == nil {
+			t.Fatalf("tests[%d]: expected error %q", i, tt.err)
+		}
+
+		if err.Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.err, err)
+		}
+
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Fatalf("tests[%d]: expected error matching %q", i, tt.kind)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	in := New()
+	var out bytes.Buffer
+	in.Stdout = &out
+
+	err := in.Builtins().Register("lookup", 1, "lookup(k) returns the value stored under k.", func(args []object.Object) (object.Object, error) {
+		k, ok := args[0].(*object.Int)
+		if !ok {
+			return nil, object.ArgTypeError(0, "int", args[0])
+		}
+		if k.Value < 0 {
+			return nil, fmt.Errorf("no value for %d", k.Value)
+		}
+		return &object.Int{Value: k.Value * 10}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input  string
+		output string
+		err    string
+	}{
+		{input: "lookup(4)", output: "40"},
+		{input: "let f = lookup\nf(1) + f(2)", output: "30"},
+		{input: "let lookup = func(k) { k }\nlookup(4)", output: "4"},
+		{input: "help(lookup)", output: "lookup(k) returns the value stored under k."},
+		{input: "type(lookup)", output: "builtin"},
+		{input: "str(1 + 1) + str(true)", output: "2true"},
+		{input: "print(1, null, 2 < 3)", output: "null"},
+		{input: "lookup(1, 2)", err: "1:1: wrong number of arguments to lookup: want 1, got 2"},
+		{input: "lookup()", err: "1:1: wrong number of arguments to lookup: want 1, got 0"},
+		{input: "1 + lookup(true)", err: "1:12: lookup: argument 1: want int, got bool"},
+		{input: "let k = 0 - 1\nlookup(k)", err: "2:1: lookup: no value for -1"},
+		{input: "len(1)", err: "1:5: len: argument 1: want str, array or map, got int"},
+		{input: "help(len(2))", err: "1:10: len: argument 1: want str, array or map, got int"},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		result, err := in.Run(context.Background(), program, object.NewEnv(nil))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("tests[%d]: expected error %q, got %v", i, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+
+		if result.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, result.String())
+		}
+	}
+
+	if out.String() != "1 null true\n" {
+		t.Fatalf("wrong print output: %q", out.String())
+	}
+
+	if err := in.Builtins().Register("lookup", 1, "", nil); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+	if err := in.Builtins().Register("if", 1, "", nil); err == nil {
+		t.Fatalf("expected keyword name to fail")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits Limits
+		err    string
+		kind   error
+	}{
+		{
+			input:  "let i = 0\nwhile true {\n\ti += 1\n}",
+			limits: Limits{MaxSteps: 1000},
+			kind:   ErrStepLimit,
+		},
+		{
+			input:  "let f = func(n) { 1 + f(n + 1) }\nf(0)",
+			limits: Limits{MaxDepth: 100},
+			err:    "1:23: maximum call depth of 100 exceeded",
+			kind:   ErrDepthLimit,
+		},
+		{
+			input:  "let s = str(1234567890)\nwhile true { s = s + s }",
+			limits: Limits{MaxAlloc: 1 << 20},
+			err:    "2:20: allocation limit of 1048576 bytes exceeded",
+			kind:   ErrAllocLimit,
+		},
+		{
+			input:  "let f = func() { {} }\nwhile true { f() }",
+			limits: Limits{MaxAlloc: 1 << 16},
+			kind:   ErrAllocLimit,
+		},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		in := New()
+		in.Limits = tt.limits
+
+		_, err := in.Run(context.Background(), program, object.NewEnv(nil))
+		if !errors.Is(err, tt.kind) {
+			t.Fatalf("tests[%d]: expected error matching %q, got %v", i, tt.kind, err)
+		}
+		if tt.err != "" && err.Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.err, err)
+		}
+
+		var rerr *Error
+		if !errors.As(err, &rerr) || !rerr.Pos.IsValid() {
+			t.Fatalf("tests[%d]: expected positioned error, got %v", i, err)
+		}
+
+		_, err = in.Run(context.Background(), program, object.NewEnv(nil))
+		if !errors.Is(err, tt.kind) {
+			t.Fatalf("tests[%d]: budget was not reset between runs: %v", i, err)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	p := parser.New(lexer.New("while true {}"))
+	program := p.ParseProgram()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := New().Run(ctx, program, object.NewEnv(nil))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	var rerr *Error
+	if !errors.As(err, &rerr) || !rerr.Pos.IsValid() {
+		t.Fatalf("expected positioned error, got %v", err)
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+		expr  string
+		trace []string
+	}{
+		{
+			input: "let div = func(a, b) { a / b }\nlet avg = func(sum, n) { div(sum, n) + 0 }\navg(10, 0)",
+			err:   "1:26: integer division by zero",
+			expr:  "(a / b)",
+			trace: []string{"div called at 2:26", "avg called at 3:1"},
+		},
+		{
+			input: "let div = func(a, b) { a / b }\nlet avg = func(sum, n) { div(sum, n) }\navg(10, 0)",
+			err:   "1:26: integer division by zero",
+			expr:  "(a / b)",
+			trace: []string{"div tail called at 2:26"},
+		},
+		{
+			input: "let rem = func(a, b) { a % b }\nlet f = rem\nf(1, 0)",
+			err:   "1:26: integer division by zero",
+			expr:  "(a % b)",
+			trace: []string{"rem called at 3:1"},
+		},
+		{
+			input: "let apply = func(f) { f(1) }\napply(2)",
+			err:   "1:23: cannot call int value 2",
+			expr:  "f(1, )",
+			trace: []string{"apply called at 2:1"},
+		},
+		{
+			input: "let add = func(a, b) { a + b }\nlet g = func() { add(1) }\ng()",
+			err:   "2:18: wrong number of arguments to add: want 2, got 1",
+			expr:  "add(1, )",
+			trace: []string{"g called at 3:1"},
+		},
+		{
+			input: "let h = func(x) { len(x) }\nh(1)",
+			err:   "1:23: len: argument 1: want str, array or map, got int",
+			expr:  "len(x, )",
+			trace: []string{"h called at 2:1"},
+		},
+		{
+			input: "func(n) { if n { 1 } }(3)",
+			err:   "1:14: non-bool n (type int) used as condition",
+			expr:  "n",
+			trace: []string{"func literal at 1:1 called at 1:1"},
+		},
+		{
+			input: "let x = 1\nx / 0",
+			err:   "2:3: integer division by zero",
+			expr:  "(x / 0)",
+		},
+	}
+
+	for i, tt := range tests {
+		_, err := run(t, tt.input)
+
+		var rerr *Error
+		if !errors.As(err, &rerr) {
+			t.Fatalf("tests[%d]: expected runtime error, got %v", i, err)
+		}
+
+		if rerr.Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.err, rerr)
+		}
+
+		if rerr.Expr != tt.expr {
+			t.Fatalf("tests[%d]: expected expression %q, got %q", i, tt.expr, rerr.Expr)
+		}
+
+		var trace []string
+		for _, frame := range rerr.Trace {
+			trace = append(trace, frame.String())
+		}
+		if fmt.Sprint(trace) != fmt.Sprint(tt.trace) {
+			t.Fatalf("tests[%d]: expected trace %q, got %q", i, tt.trace, trace)
+		}
+	}
+}
+
+func TestIntSemantics(t *testing.T) {
+	const (
+		min = math.MinInt64
+		max = math.MaxInt64
+	)
+
+	type intCase struct{ a, b, want int64 }
+
+	tests := []struct {
+		op    token.Token
+		cases []intCase
+	}{
+		{token.ADD, []intCase{{1, 2, 3}, {max, 1, min}, {min, -1, max}}},
+		{token.SUB, []intCase{{1, 2, -1}, {min, 1, max}, {max, -1, min}}},
+		{token.MUL, []intCase{{-3, 4, -12}, {max, 2, -2}, {min, -1, min}, {1 << 32, 1 << 32, 0}}},
+		{token.DIV, []intCase{{7, 2, 3}, {-7, 2, -3}, {7, -2, -3}, {-7, -2, 3}, {min, -1, min}}},
+		{token.MOD, []intCase{{7, 3, 1}, {-7, 3, -1}, {7, -3, 1}, {-7, -3, -1}, {min, -1, 0}}},
+		{token.AND, []intCase{{12, 10, 8}, {-1, 5, 5}, {min, max, 0}}},
+		{token.OR, []intCase{{12, 10, 14}, {min, max, -1}}},
+		{token.XOR, []intCase{{12, 10, 6}, {-1, 5, -6}}},
+		{token.LSHIFT, []intCase{{1, 3, 8}, {1, 63, min}, {1, 64, 0}, {-1, 100, 0}, {3, 0, 3}}},
+		{token.RSHIFT, []intCase{{8, 3, 1}, {-8, 1, -4}, {-1, 63, -1}, {max, 64, 0}, {min, 64, -1}}},
+	}
+
+	assignOps := map[token.Token]token.Token{
+		token.ADD_ASSIGN:    token.ADD,
+		token.SUB_ASSIGN:    token.SUB,
+		token.MUL_ASSIGN:    token.MUL,
+		token.DIV_ASSIGN:    token.DIV,
+		token.MOD_ASSIGN:    token.MOD,
+		token.AND_ASSIGN:    token.AND,
+		token.OR_ASSIGN:     token.OR,
+		token.XOR_ASSIGN:    token.XOR,
+		token.LSHIFT_ASSIGN: token.LSHIFT,
+		token.RSHIFT_ASSIGN: token.RSHIFT,
+	}
+
+	prefix := []struct {
+		op    token.Token
+		cases []intCase
+	}{
+		{token.SUB, []intCase{{a: 5, want: -5}, {a: min, want: min}, {a: max, want: min + 1}}},
+		{token.TILDE, []intCase{{a: 0, want: -1}, {a: min, want: max}, {a: -6, want: 5}}},
+	}
+
+	check := func(input string, want int64) {
+		t.Helper()
+
+		obj, err := run(t, input)
+		if err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+		if i, ok := obj.(*object.Int); !ok || i.Value != want {
+			t.Fatalf("%q: expected %d, got %s", input, want, obj)
+		}
+	}
+
+	covered := make(map[token.Token]bool)
+	for _, tt := range tests {
+		for _, c := range tt.cases {
+			check(fmt.Sprintf("(%d) %s (%d)", c.a, tt.op, c.b), c.want)
+		}
+		covered[tt.op] = true
+	}
+	for assign, op := range assignOps {
+		for _, tt := range tests {
+			if tt.op != op {
+				continue
+			}
+			for _, c := range tt.cases {
+				check(fmt.Sprintf("let x = (%d)\nx %s (%d)\nx", c.a, assign, c.b), c.want)
+			}
+			covered[assign] = true
+		}
+	}
+	for _, tt := range prefix {
+		for _, c := range tt.cases {
+			check(fmt.Sprintf("%s(%d)", tt.op, c.a), c.want)
+		}
+		covered[tt.op] = true
+	}
+
+	// The arithmetic and bitwise operators are declared contiguously.
+	for tok := token.ADD; tok <= token.RSHIFT_ASSIGN; tok++ {
+		if !covered[tok] {
+			t.Errorf("operator %s has no conformance cases", tok)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"1 / 0", "1:3: integer division by zero"},
+		{"1 % 0", "1:3: integer division by zero"},
+		{"let x = 1\nx /= 0", "2:3: integer division by zero"},
+		{"let x = 1\nx %= 0", "2:3: integer division by zero"},
+		{"1 << -1", "1:3: negative shift count -1"},
+		{"1 >> -64", "1:3: negative shift count -64"},
+		{"9223372036854775808", "1:1: integer literal 9223372036854775808 out of range"},
+		{"-9223372036854775809", "1:2: integer literal 9223372036854775809 out of range"},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+	}
+}
+
+func TestBigInts(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775808 - 1", "-9223372036854775809"},
+		{"-(-9223372036854775808)", "9223372036854775808"},
+		{"-9223372036854775808 / -1", "9223372036854775808"},
+		{"-9223372036854775808 % -1", "0"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"-3037000500 * 3037000500", "-9223372037000250000"},
+		{"1 << 64", "18446744073709551616"},
+		{"-1 << 100", "-1267650600228229401496703205376"},
+		{"(1 << 100) >> 99", "2"},
+		{"(-1 << 100) >> 1000", "-1"},
+		{"(1 << 100) >> 1000", "0"},
+		{"~(1 << 64)", "-18446744073709551617"},
+		{"(1 << 64) & ((1 << 65) - 1)", "18446744073709551616"},
+		{"(1 << 64) | 1", "18446744073709551617"},
+		{"(1 << 64) ^ (1 << 64)", "0"},
+		{"-(1 << 70) / 1000", "-1180591620717411303"},
+		{"-(1 << 70) % 1000", "-424"},
+		{"99999999999999999999 - 99999999999999999998", "1"},
+		{"(1 << 64) == 18446744073709551616", "true"},
+		{"(1 << 64) != 1 << 63", "true"},
+		{"(1 << 64) > 9223372036854775807", "true"},
+		{"-(1 << 64) < -9223372036854775808", "true"},
+		{"(1 << 64) <= (1 << 64) && (1 << 64) >= (1 << 64)", "true"},
+		{"let a = 9223372036854775807\na += 1\na -= 1\na", "9223372036854775807"},
+		{"let f = func(n) { if n == 0 { return 1 }\nn * f(n - 1) }\nf(25)", "15511210043330985984000000"},
+		{"type(1 << 64)", "int"},
+		{"str(1 << 64)", "18446744073709551616"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		in := New()
+		in.BigInts = true
+
+		obj, err := in.Run(context.Background(), program, object.NewEnv(nil))
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if obj.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, obj)
+		}
+
+		// Values that fit in an int64 must stay small.
+		if b, ok := obj.(*object.BigInt); ok && b.Value.IsInt64() {
+			t.Fatalf("tests[%d]: %s was not demoted", i, obj)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"(1 << 64) / 0", "1:11: integer division by zero"},
+		{"(1 << 64) % (1 - 1)", "1:11: integer division by zero"},
+		{"(1 << 64) << -1", "1:11: negative shift count -1"},
+		{"1 << (1 << 64)", "1:3: shift count 18446744073709551616 too large"},
+		{"(1 << 64) + true", "1:11: invalid operation: operator + not defined on int and bool"},
+	}
+
+	for i, tt := range errs {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		in := New()
+		in.BigInts = true
+
+		_, err := in.Run(context.Background(), program, object.NewEnv(nil))
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+	}
+}
+
+func TestTruthiness(t *testing.T) {
+	tests := []struct {
+		input  string
+		strict string
+		truthy string
+	}{
+		{"true && false", "false", "false"},
+		{"false || true", "true", "true"},
+		{"!false", "true", "true"},
+		{"false && 1", "false", "false"},
+		{"true || 1", "true", "true"},
+		{"true && 1", "1:9: non-bool 1 (type int) used as operand of &&", "1"},
+		{"1 || 2", "1:1: non-bool 1 (type int) used as operand of ||", "1"},
+		{"0 || 2", "1:1: non-bool 0 (type int) used as operand of ||", "2"},
+		{"null && undefined", "1:1: non-bool null (type null) used as operand of &&", "null"},
+		{"0 || null || false", "1:1: non-bool 0 (type int) used as operand of ||", "false"},
+		{"!0", "1:1: invalid operation: !0 (operator ! not defined on int)", "true"},
+		{"!!func() {}", "1:2: invalid operation: !func() { } (operator ! not defined on func)", "true"},
+		{"if 0 { 1 } else { 2 }", "1:4: non-bool 0 (type int) used as condition", "2"},
+		{"if -1 { 1 } else { 2 }", "1:4: non-bool (-1) (type int) used as condition", "1"},
+		{"if null { 1 } else { 2 }", "1:4: non-bool null (type null) used as condition", "2"},
+		{"if len { 1 } else { 2 }", "1:4: non-bool len (type builtin) used as condition", "1"},
+		{"let n = 3\nlet i = 0\nwhile n { n -= 1\ni += 1 }\ni", "3:7: non-bool n (type int) used as condition", "3"},
+	}
+
+	for i, tt := range tests {
+		for _, mode := range []Truthiness{Strict, Truthy} {
+			want := tt.strict
+			if mode == Truthy {
+				want = tt.truthy
+			}
+
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			if program == nil {
+				t.Fatalf("tests[%d]: parse error", i)
+			}
+			in := New()
+			in.Truthiness = mode
+
+			var got string
+			obj, err := in.Run(context.Background(), program, object.NewEnv(nil))
+			if err != nil {
+				got = err.Error()
+			} else {
+				got = obj.String()
+			}
+			if got != want {
+				t.Fatalf("tests[%d] (mode %d): expected %q, got %q", i, mode, want, got)
+			}
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		// Each call of the generator gets its own captured n.
+		{`
+let counter = func() {
+	let n = 0
+	func() { n += 1 }
+}
+let a = counter()
+let b = counter()
+a()
+a()
+b()
+a() * 10 + b()`, "32"},
+		// Captured variables are shared, not copied.
+		{`
+let x = 1
+let get = func() { x }
+let set = func(v) { x = v }
+set(5)
+get() + x`, "10"},
+		{`
+let account = func(balance) {
+	let deposit = func(n) { balance += n }
+	let withdraw = func(n) { balance -= n }
+	func(op, n) { if op == 0 { deposit(n) } else { withdraw(n) } }
+}
+let acct = account(100)
+acct(0, 50)
+acct(1, 30)`, "120"},
+		// Nested closures capture every enclosing scope.
+		{"let add3 = func(a) { func(b) { func(c) { a * 100 + b * 10 + c } } }\nadd3(1)(2)(3)", "123"},
+		{`
+let outer = func() {
+	let n = 1
+	let middle = func() {
+		let m = 10
+		func() { n += m }
+	}
+	let inner = middle()
+	inner()
+	inner()
+	n
+}
+outer()`, "21"},
+		// Each iteration's block is a new scope, so lets inside a loop are
+		// captured separately while the loop variable itself is shared.
+		{`
+let first = null
+let second = null
+let i = 0
+while i < 2 {
+	let j = i
+	let f = func() { j * 10 + i }
+	if i == 0 { first = f } else { second = f }
+	i += 1
+}
+first() * 100 + second()`, "212"},
+		{`
+let compose = func(f, g) { func(x) { f(g(x)) } }
+let inc = func(x) { x + 1 }
+let double = func(x) { x * 2 }
+compose(inc, double)(5) * 100 + compose(double, inc)(5)`, "1112"},
+		{`
+let each = func(n, f) {
+	let i = 0
+	while i < n {
+		f(i)
+		i += 1
+	}
+}
+let sum = 0
+each(5, func(i) { sum += i })
+sum`, "10"},
+		// A closure shadowing a captured name does not affect the outer one.
+		{"let n = 1\nlet f = func(n) { n += 1 }\nf(5) * 10 + n", "61"},
+		{"let n = 1\nlet f = func() { let n = 7\nn }\nf() * 10 + n", "71"},
+	}
+
+	for i, tt := range tests {
+		obj, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if obj.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, obj)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"let count = func(n, acc) { if n == 0 { return acc }\nreturn count(n - 1, acc + 1) }\ncount(100000, 0)", "100000"},
+		{"let count = func(n, acc) { if n == 0 { acc } else { count(n - 1, acc + 1) } }\ncount(100000, 0)", "100000"},
+		{"let count = func(n, acc) { if n == 0 { acc } else { { let m = n - 1\ncount(m, acc + 1) } } }\ncount(100000, 0)", "100000"},
+		{`
+let even = func(n) { if n == 0 { true } else { odd(n - 1) } }
+let odd = func(n) { if n == 0 { false } else { even(n - 1) } }
+even(100001)`, "false"},
+		{`
+let loop = func(n) {
+	while true {
+		if n == 0 { return 7 }
+		return loop(n - 1)
+	}
+}
+loop(100000)`, "7"},
+		// Tail calls to closures run in the callee's environment.
+		{`
+let make = func(k) { func(n) { n * k } }
+let triple = make(3)
+let f = func(n) { let k = 100
+triple(n) }
+f(5)`, "15"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		in := New()
+		in.Limits = Limits{MaxDepth: 10}
+
+		obj, err := in.Run(context.Background(), program, object.NewEnv(nil))
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if obj.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, obj)
+		}
+	}
+
+	// Calls that are not in tail position still use a frame each.
+	nonTail := []string{
+		"let f = func(n) { if n == 0 { 0 } else { 1 + f(n - 1) } }\nf(100)",
+		"let f = func(n) { if n == 0 { return 0 }\nlet r = f(n - 1)\nr }\nf(100)",
+		"let f = func(n) { while n > 0 { f(n - 1)\nbreak }\nn }\nf(100)",
+		"let f = func(n) { if n == 0 { true } else { f(n - 1) && true } }\nf(100)",
+	}
+
+	for i, input := range nonTail {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		in := New()
+		in.Limits = Limits{MaxDepth: 10}
+
+		if _, err := in.Run(context.Background(), program, object.NewEnv(nil)); !errors.Is(err, ErrDepthLimit) {
+			t.Fatalf("nonTail[%d]: expected depth limit, got %v", i, err)
+		}
+	}
+}
+
+func TestExprValues(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"{ 1\n2 }", "2"},
+		{"{}", "null"},
+		{"{ 1\nlet a = 2 }", "null"},
+		{"let x = { let a = 2\na * 3 }\nx", "6"},
+		{"{ { 4 } }", "4"},
+		{"let x = if true { 1 }\nx", "1"},
+		{"let x = if false { 1 }\nx", "null"},
+		{"let x = if false { 1 } else if false { 2 }\nx", "null"},
+		{"let x = if false { 1 } else { let y = 2 }\nx", "null"},
+		{"(if 1 < 2 { 10 } else { 20 }) + 1", "11"},
+		{"let x = while false { 1 }\nx", "null"},
+		{"let i = 0\nlet x = while i < 3 { i += 1 }\nx", "null"},
+		{"let x = while true { break 5 }\nx", "5"},
+		{"let x = while true { break }\nx", "null"},
+		{`
+let i = 0
+let found = while i < 100 {
+	i += 1
+	if i * i > 50 { break i }
+}
+found`, "8"},
+		{`
+let found = func(n) {
+	let i = 0
+	while i < n {
+		i += 1
+		if i % 7 == 0 { break i }
+	}
+}
+found(20) * 100 + (if found(5) == null { 1 } else { 2 })`, "701"},
+		// break applies to the innermost loop only.
+		{`
+let i = 0
+let x = while true {
+	let inner = while true { break i + 10 }
+	i += 1
+	if i == 3 { break inner }
+}
+x`, "12"},
+	}
+
+	for i, tt := range tests {
+		obj, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if obj.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, obj)
+		}
+	}
+}
+
+func TestForExpr(t *testing.T) {
+	m := object.NewMap()
+	m.Set(&object.String{Value: "b"}, &object.Int{Value: 2})
+	m.Set(&object.String{Value: "a"}, &object.Int{Value: 1})
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"let n = 0\nfor i in 0..5 { n += i }\nn", "10"},
+		{"let n = 0\nfor i in 0..=5 { n += i }\nn", "15"},
+		{"let n = 0\nfor i in 5..0 { n += 1 }\nn", "0"},
+		{"let n = 0\nfor i in 3..=3 { n += i }\nn", "3"},
+		{"let n = 0\nfor i in -2..2 { n = n * 10 + i + 2 }\nn", "123"},
+		{"let n = 0\nfor i in 9223372036854775805..=9223372036854775807 { n += 1 }\nn", "3"},
+		{"let r = 1..=3\nlet n = 0\nfor i in r { n += i }\nfor i in r { n += i }\nn", "12"},
+		{"str(0..10)", "0..10"},
+		{"str(1..=2)", "1..=2"},
+		{"type(0..1)", "range"},
+		{"(0..2) == (0..2)", "true"},
+		{"(0..2) == (0..=2)", "false"},
+		{"let n = 0\nfor x in xs { n = n * 10 + x }\nn", "123"},
+		{"let s = str(0)\nfor k in m { s = s + k }\ns", "0ba"},
+		{"for i in 0..10 { if i * i > 20 { break i } }", "5"},
+		{"for i in 0..10 { i }", "null"},
+		{"for i in 0..0 { break 1 }", "null"},
+		{"let n = 0\nfor i in 0..10 { if i % 3 != 0 { continue }\nn += 1 }\nn", "4"},
+		{"let n = 0\nfor i in 0..3 { for j in 0..3 { if j > i { break }\nn += 1 } }\nn", "6"},
+		{"let i = 7\nfor i in 0..3 {}\ni", "7"},
+		{"let g = null\nfor i in 0..3 { if i == 1 { g = func() { i } } }\ng()", "1"},
+		{"let f = func() { for i in 0..10 { if i == 4 { return i * 10 } } }\nf()", "40"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: parse error", i)
+		}
+		env := object.NewEnv(nil)
+		env.Define("xs", &object.Array{Elems: []object.Object{&object.Int{Value: 1}, &object.Int{Value: 2}, &object.Int{Value: 3}}})
+		env.Define("m", m)
+
+		obj, err := New().Run(context.Background(), program, env)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if obj.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, obj)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"for x in 5 {}", "1:10: cannot iterate over 5 (type int)"},
+		{"for x in true..2 {}", "1:14: invalid operation: operator .. not defined on bool and int"},
+		{"for x in 0..10 { x = x / 0 }", "1:24: integer division by zero"},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+	}
+}
+
+func TestMatchExpr(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"match 2 { 1 => 10, 2 => 20, _ => 30 }", "20"},
+		{"match 5 { 1 => 10, 2 => 20, _ => 30 }", "30"},
+		{"match 5 { 1 => 10 }", "null"},
+		{"match 5 {}", "null"},
+		{"match -3 { 3 => 1, -3 => 2 }", "2"},
+		{"match true { false => 0, true => 1 }", "1"},
+		{"match null { 0 => 0, false => 1, null => 2 }", "2"},
+		{"match 1 { true => 0, _ => 1 }", "1"},
+		{`match "b" { "a" => 1, "b" => 2 }`, "2"},
+		{"match 7 { n => n * 2 }", "14"},
+		{"let n = 1\nmatch 7 { n => n }\nn", "1"},
+		{"let sign = func(x) { match x { 0 => 0, n if n < 0 => -1, _ => 1 } }\nsign(-5) * 100 + sign(0) * 10 + sign(9)", "-99"},
+		{"match 4 { n if n % 2 == 1 => 1, n if n > 2 => 2, _ => 3 }", "2"},
+		{"let calls = 0\nlet f = func() { calls += 1\n3 }\nmatch f() { 1 => 1, 2 => 2, 3 => 3 }\ncalls", "1"},
+		{"let fizz = func(i) { match i % 15 { 0 => 15, _ => match i % 5 { 0 => 5, _ => match i % 3 { 0 => 3, _ => 0 } } } }\nfizz(30) * 100 + fizz(10) * 10 + fizz(9)", "1553"},
+		{"let count = func(n, acc) { match n { 0 => acc, _ => count(n - 1, acc + 1) } }\ncount(100000, 0)", "100000"},
+		{"match 9223372036854775807 { -9223372036854775808 => 0, 9223372036854775807 => 1 }", "1"},
+		{"for i in 0..10 { match i { 3 => { break i * 10 }, _ => {} } }", "30"},
+		{"match (1, 2) { (1, 1) => 1, (1, y) => y * 10, _ => 3 }", "20"},
+		{"match (1, 2) { (a, b, c) => 1, [a, b] => 2, _ => 3 }", "3"},
+		{"match [1, 2, 3] { [] => 0, [x] => x, [x, ...rest] => rest }", "[2, 3]"},
+		{`match [("a", 1)] { [("b", n)] => n, [("a", n)] => -n }`, "-1"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: parse error", i)
+		}
+		in := New()
+		in.Limits = Limits{MaxDepth: 100}
+
+		obj, err := in.Run(context.Background(), program, object.NewEnv(nil))
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if obj.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, obj)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"match 1 { n if n => 1 }", "1:16: non-bool n (type int) used as match guard"},
+		{"match 1 / 0 { _ => 1 }", "1:9: integer division by zero"},
+		{"match 1 { 1 => 1 / 0 }", "1:18: integer division by zero"},
+		{"match 1 { n if n / 0 == 1 => 1 }", "1:18: integer division by zero"},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"let (a, b) = (1, 2)\na * 10 + b", "12"},
+		{"let (x,) = (7,)\nx", "7"},
+		{"let ((a, b), c) = ((1, 2), 3)\na + b + c", "6"},
+		{"let [x, y, ...rest] = [1, 2, 3, 4]\nrest", "[3, 4]"},
+		{"let [x, y, ...rest] = [1, 2]\nrest", "[]"},
+		{"let [_, second, ...] = [1, 2, 3]\nsecond", "2"},
+		{"let [] = []\n0", "0"},
+		{"let (a, [b, c]) = (1, [2, 3])\na + b + c", "6"},
+		{"let mut (a, b) = (1, 2)\nlet t = a\na = b\nb = t\n(a, b)", "(2, 1)"},
+		{"let swap = func((a, b)) { (b, a) }\nswap((1, 2))", "(2, 1)"},
+		{"let head = func([x, ...]) { x }\nhead([5, 6])", "5"},
+		{"let f = func(n, (a, b)) { if n == 0 { return a + b }\nf(n - 1, (b, a + b)) }\nf(10, (0, 1))", "144"},
+		{"(1, 2) == (1, 2) && (1, (2,)) != (1, (3,))", "true"},
+		{"[1] == [1]", "false"},
+		{`type((1,)) + type([])`, "tuplearray"},
+	}
+
+	for i, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if result.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, result.String())
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"let (a, b) = 1", "1:5: cannot destructure 1 (type int) with tuple pattern (a, b)"},
+		{"let (a, b) = (1, 2, 3)", "1:5: tuple pattern (a, b) needs 2 elements, got 3"},
+		{"let (a, b) = [1, 2]", "1:5: cannot destructure [1, 2] (type array) with tuple pattern (a, b)"},
+		{"let [x, y, ...rest] = [1]", "1:5: array pattern [x, y, ...rest] needs at least 2 elements, got 1"},
+		{"let [x, y] = [1, 2, 3]", "1:5: array pattern [x, y] needs 2 elements, got 3"},
+		{"let (a, [b, c]) = (1, [2])", "1:9: array pattern [b, c] needs 2 elements, got 1"},
+		{"let f = func(x, (a, b)) { a }\nf(1, 2)", "1:17: cannot destructure 2 (type int) with tuple pattern (a, b)"},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+		if !errors.Is(err, ErrPattern) {
+			t.Fatalf("errs[%d]: expected error matching %q", i, ErrPattern)
+		}
+	}
+
+	_, err := run(t, "let f = func((a, b)) { a }\nf(1)")
+	var rerr *Error
+	if !errors.As(err, &rerr) || len(rerr.Trace) != 1 || rerr.Trace[0].String() != "f called at 2:1" {
+		t.Fatalf("expected the call in the stack trace, got %#v", err)
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"let f = func(a, b = 10) { a + b }\nf(1) * 100 + f(1, 2)", "1103"},
+		{"let f = func(a, b = a * 2, c = a + b) { (a, b, c) }\nf(1)", "(1, 2, 3)"},
+		{"let calls = 0\nlet f = func(a = { calls += 1 }) { a }\nf(0)\nf()\nf()\ncalls", "2"},
+		{"let f = func(a, ...rest) { rest }\nf(1)", "[]"},
+		{"let f = func(a, ...rest) { rest }\nf(1, 2, 3)", "[2, 3]"},
+		{"let f = func(a, b = 2, ...rest) { (a, b, rest) }\nf(1, 5, 6)", "(1, 5, [6])"},
+		{"let f = func(...) { 0 }\nf(1, 2, 3)", "0"},
+		{"let f = func((x, y) = (1, 2)) { x + y }\nf()", "3"},
+		{"let f = func(a, b, c) { a * 100 + b * 10 + c }\nf(...[1, 2, 3])", "123"},
+		{"let f = func(a, b, c) { a * 100 + b * 10 + c }\nf(1, ...(2,), ...[], 3)", "123"},
+		{"let sum = func(...xs) { let n = 0\nfor x in xs { n += x }\nn }\nsum(...[1, 2], 3, ...(4, 5))", "15"},
+		{"let count = func(n, acc = 0) { if n == 0 { return acc }\ncount(n - 1, acc + 1) }\ncount(100000)", "100000"},
+		{"len(...[[1, 2]])", "2"},
+	}
+
+	for i, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if result.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, result.String())
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+		kind  error
+	}{
+		{"let f = func(a, b = 1) { a }\nf()", "2:1: wrong number of arguments to f: want 1 to 2, got 0", ErrArgCount},
+		{"let f = func(a, b = 1) { a }\nf(1, 2, 3)", "2:1: wrong number of arguments to f: want 1 to 2, got 3", ErrArgCount},
+		{"let f = func(a, b, ...rest) { a }\nf(1)", "2:1: wrong number of arguments to f: want at least 2, got 1", ErrArgCount},
+		{"let f = func(a) { a }\nf(...[1, 2])", "2:1: wrong number of arguments to f: want 1, got 2", ErrArgCount},
+		{"let f = func(a) { a }\nf(...1)", "2:6: cannot spread 1 (type int) into arguments", ErrType},
+		{"let f = func(a = 1 / 0) { a }\nf()", "1:20: integer division by zero", ErrDivisionByZero},
+		{"len(1, ...[2])", "1:1: wrong number of arguments to len: want 1, got 2", ErrArgCount},
+		{"str(...[1], 2)", "1:1: wrong number of arguments to str: want 1, got 2", ErrArgCount},
+		{`len(...(1,))`, "1:1: len: argument 1: want str, array or map, got int", nil},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Fatalf("errs[%d]: expected error matching %q", i, tt.kind)
+		}
+	}
+}
+
+func TestNamedArgs(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"let f = func(host, port = 80, tls = false) { (host, port, tls) }\nf(\"a\", tls: true)", `(a, 80, true)`},
+		{"let f = func(host, port = 80, tls = false) { (host, port, tls) }\nf(tls: true, host: \"b\", port: 1)", `(b, 1, true)`},
+		{"let f = func(a, b = a + 1) { b }\nf(a: 1)", "2"},
+		{"let f = func(a, b, ...rest) { (a, b, rest) }\nf(1, b: 2)", "(1, 2, [])"},
+		{"let f = func(a, b) { a - b }\nf(...[5], b: 3)", "2"},
+		{"let order = \"\"\nlet f = func(a, b) { 0 }\nf(b: { order += \"b\" }, a: { order += \"a\" })\norder", "ba"},
+		{"let count = func(n, acc = 0) { if n == 0 { return acc }\ncount(acc: acc + 1, n: n - 1) }\ncount(100000)", "100000"},
+	}
+
+	for i, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if result.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, result.String())
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+		kind  error
+	}{
+		{"let f = func(a, b) { a }\nf(1, c: 2)", "2:6: unknown parameter c in call to f", ErrArgName},
+		{"let f = func(a, b) { a }\nf(1, a: 2)", "2:6: parameter a of f is already given by position", ErrArgName},
+		{"let f = func(a, b) { a }\nf(b: 2)", "2:1: missing argument for parameter a in call to f", ErrArgCount},
+		{"let f = func((a, b)) { a }\nf(a: 1)", "2:3: unknown parameter a in call to f", ErrArgName},
+		{"let f = func(a, ...rest) { a }\nf(1, rest: [])", "2:6: unknown parameter rest in call to f", ErrArgName},
+		{"len(x: 1)", "1:5: cannot pass named arguments to builtin len", ErrArgName},
+		{"let x = 1\nx(a: 1)", "2:1: cannot call int value 1", ErrNotCallable},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+		if !errors.Is(err, tt.kind) {
+			t.Fatalf("errs[%d]: expected error matching %q", i, tt.kind)
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"struct P { x, y }\nlet p = P { y: 2, x: 1 }\np", "P { x: 1, y: 2 }"},
+		{"struct P { x, y }\nlet p = P { x: 1, y: 2 }\np.x * 10 + p.y", "12"},
+		{"struct P { x }\nlet p = P { x: 1 }\np.x = 5\np.x += 2\np.x", "7"},
+		{"struct P { x }\nlet p = P { x: [1] }\nlet q = p\nq.x = []\np", "P { x: [] }"},
+		{"struct Node { value, next }\nlet n = Node { value: 1, next: null }\nn.next = n\nn", "Node { value: 1, next: Node {...} }"},
+		{"struct Empty {}\nEmpty {}", "Empty {}"},
+		{"struct P { x }\nlet a = P { x: 1 }\n(a == a, a == P { x: 1 })", "(true, false)"},
+		{"struct P { x }\n(type(P), type(P { x: 1 }), str(P))", "(type, struct, struct P)"},
+		{"struct P { x }\nlet f = func(p) { p.x }\nf(P { x: 3 })", "3"},
+		{"struct P { x }\nif (P { x: true }).x { 1 } else { 2 }", "1"},
+	}
+
+	for i, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if result.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, result.String())
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+		kind  error
+	}{
+		{"struct P { x, y }\nP { x: 1 }", "2:3: missing field y in P literal", ErrField},
+		{"struct P { x }\nP { x: 1, z: 2 }", "2:11: unknown field z in P literal", ErrField},
+		{"struct P { x }\nlet p = P { x: 1 }\np.z", "3:3: p (type P) has no field z", ErrField},
+		{"struct P { x }\nlet p = P { x: 1 }\np.z = 1", "3:3: p (type P) has no field z", ErrField},
+		{"let n = 1\nn { x: 1 }", "2:1: n (type int) is not a struct type", ErrType},
+		{"let n = 1\nn.x = 2", "2:5: cannot assign to field x of n (type int)", ErrType},
+		{"struct P { x }\nlet p = P { x: 1 }\np.x.y = 2", "3:7: cannot assign to field y of p.x (type int)", ErrType},
+		{"struct P { x }\nlet p = P { x: \"a\" }\np.x -= 1", "3:5: invalid operation: operator - not defined on str and int", nil},
+	}
+
+	for i, tt := range errs {
+		_, err := run(t, tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Fatalf("errs[%d]: expected %q, got %v", i, tt.err, err)
+		}
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Fatalf("errs[%d]: expected error matching %q", i, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"oasis/ast"
+	"oasis/object"
+	"oasis/token"
+)
+
+// Limits bounds the resources a single Run or Call may use. A zero field
+// means no limit.
+type Limits struct {
+	// MaxSteps is the number of AST nodes that may be evaluated.
+	MaxSteps int64
+	// MaxDepth is the maximum number of nested function calls.
+	MaxDepth int
+	// MaxAlloc is the approximate number of bytes the program may
+	// allocate for strings, arrays, maps, scopes and closures. It counts
+	// every allocation, not just the memory that is still in use.
+	MaxAlloc int64
+}
+
+var (
+	ErrStepLimit  = errors.New("step limit exceeded")
+	ErrDepthLimit = errors.New("call depth limit exceeded")
+	ErrAllocLimit = errors.New("allocation limit exceeded")
+)
+
+// checkInterval is how many steps pass between checks of the context.
+const checkInterval = 1024
+
+// Approximate sizes charged against Limits.MaxAlloc.
+const (
+	envSize    = 64
+	funcSize   = 32
+	stringSize = 16
+	arraySize  = 24
+	elemSize   = 16
+	mapSize    = 48
+	pairSize   = 48
+	bigIntSize = 32
+	wordSize   = 8
+)
+
+// enter starts an evaluation. The outermost one resets the budgets and
+// installs ctx; host callbacks re-entering the interpreter share them.
+func (in *Interpreter) enter(ctx context.Context) error {
+	if in.active == 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		in.ctx = ctx
+		in.steps = 0
+		in.allocated = 0
+		in.depth = 0
+		in.frames = in.frames[:0]
+	}
+	in.active++
+	return nil
+}
+
+func (in *Interpreter) leave() {
+	in.active--
+}
+
+func (in *Interpreter) step(node ast.Node) error {
+	in.steps++
+
+	if in.Limits.MaxSteps > 0 && in.steps > in.Limits.MaxSteps {
+		return errorf(node.Pos(), ErrStepLimit, "step limit of %d exceeded", in.Limits.MaxSteps)
+	}
+
+	if in.steps%checkInterval == 0 {
+		if err := in.ctx.Err(); err != nil {
+			return errorf(node.Pos(), err, "execution stopped: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (in *Interpreter) alloc(pos token.Pos, n int64) error {
+	in.allocated += n
+
+	if in.Limits.MaxAlloc > 0 && in.allocated > in.Limits.MaxAlloc {
+		return errorf(pos, ErrAllocLimit, "allocation limit of %d bytes exceeded", in.Limits.MaxAlloc)
+	}
+	return nil
+}
+
+// track charges the size of a newly created value.
+func (in *Interpreter) track(pos token.Pos, obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.String:
+		return in.alloc(pos, stringSize+int64(len(obj.Value)))
+	case *object.Array:
+		return in.alloc(pos, arraySize+elemSize*int64(len(obj.Elems)))
+	case *object.Tuple:
+		return in.alloc(pos, arraySize+elemSize*int64(len(obj.Elems)))
+	case *object.Struct:
+		return in.alloc(pos, arraySize+elemSize*int64(len(obj.Fields)))
+	case *object.Map:
+		return in.alloc(pos, mapSize+pairSize*int64(obj.Len()))
+	case *object.BigInt:
+		return in.alloc(pos, bigIntSize+int64(len(obj.Value.Bits()))*wordSize)
+	}
+	return nil
+}
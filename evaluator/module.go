@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"errors"
+	"oasis/ast"
+	"oasis/object"
+)
+
+var ErrImport = errors.New("import failed")
+
+// Importer finds the modules named by import statements.
+type Importer interface {
+	// Import returns the file name and program of the module imported by
+	// stmt. The importing file is stmt.Pos().File. The same file must
+	// always be reported under the same name.
+	Import(stmt *ast.ImportStmt) (file string, program *ast.Program, err error)
+}
+
+// evalImportStmt binds the module imported by node. Each file runs once,
+// the first time it is imported; later imports share its module value.
+func (in *Interpreter) evalImportStmt(node *ast.ImportStmt, env *object.Env) (object.Object, error) {
+	if in.Importer == nil {
+		return nil, errorf(node.Path.Pos(), ErrImport, "cannot import %s: no importer configured", node.Path)
+	}
+
+	file, program, err := in.Importer.Import(node)
+	if err != nil {
+		return nil, errorf(node.Path.Pos(), ErrImport, "cannot import %s: %s", node.Path, err)
+	}
+
+	mod, ok := in.modules[file]
+	if ok && mod == nil {
+		return nil, errorf(node.Path.Pos(), ErrImport, "import cycle through %s", file)
+	}
+	if !ok {
+		in.modules[file] = nil
+		if mod, err = in.runModule(node.Name.Value, file, program); err != nil {
+			delete(in.modules, file)
+			return nil, err
+		}
+		in.modules[file] = mod
+	}
+
+	env.Define(node.Name.Value, mod)
+	return object.Null, nil
+}
+
+// runModule evaluates the top level of a module in a fresh scope. A
+// top-level return ends the module early.
+func (in *Interpreter) runModule(name, file string, program *ast.Program) (*object.Module, error) {
+	if err := in.alloc(program.Pos(), envSize); err != nil {
+		return nil, err
+	}
+	mod := &object.Module{Name: name, Path: file, Env: object.NewEnv(nil)}
+
+	for _, stmt := range program.Stmts {
+		if _, err := in.eval(stmt, mod.Env); err != nil {
+			var ret *returnSignal
+			if errors.As(err, &ret) {
+				break
+			}
+			return nil, unhandled(err)
+		}
+	}
+
+	return mod, nil
+}
+
+// evalSelectorExpr evaluates x.Name, which must name a field of the
+// struct x or an exported member of the module x.
+func (in *Interpreter) evalSelectorExpr(node *ast.SelectorExpr, env *object.Env) (object.Object, error) {
+	x, err := in.eval(node.X, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := x.(*object.Struct); ok {
+		i, err := field(node, s)
+		if err != nil {
+			return nil, err
+		}
+		return s.Fields[i], nil
+	}
+
+	mod, ok := x.(*object.Module)
+	if !ok {
+		return nil, errorf(node.Dot, ErrType, "%s (type %s) has no member %s", node.X, x.Type(), node.Sel)
+	}
+
+	val, ok := mod.Lookup(node.Sel.Value)
+	if !ok {
+		return nil, errorf(node.Sel.Pos(), ErrUndefined, "undefined: %s.%s", mod.Name, node.Sel)
+	}
+	if !object.IsExported(node.Sel.Value) {
+		return nil, errorf(node.Sel.Pos(), ErrUndefined, "cannot refer to unexported name %s.%s", mod.Name, node.Sel)
+	}
+	return val, nil
+}
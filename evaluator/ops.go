@@ -0,0 +1,136 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"oasis/ast"
+	"oasis/object"
+	"oasis/token"
+)
+
+func (in *Interpreter) evalPrefixExpr(node *ast.PrefixExpr, right object.Object) (object.Object, error) {
+	switch node.Op {
+	case token.SUB:
+		if right, ok := right.(*object.Int); ok && !(in.BigInts && right.Value == math.MinInt64) {
+			return &object.Int{Value: -right.Value}, nil
+		}
+		if right, ok := toBig(right); ok {
+			return object.NewBigInt(new(big.Int).Neg(right)), nil
+		}
+	case token.TILDE:
+		if right, ok := right.(*object.Int); ok {
+			return &object.Int{Value: ^right.Value}, nil
+		}
+		if right, ok := toBig(right); ok {
+			return object.NewBigInt(new(big.Int).Not(right)), nil
+		}
+	case token.NOT:
+		if in.Truthiness == Truthy {
+			return object.NewBool(!truthy(right)), nil
+		}
+		if right, ok := right.(*object.Bool); ok {
+			return object.NewBool(!right.Value), nil
+		}
+	}
+
+	return nil, errorf(node.OpPos, ErrType, "invalid operation: %s%s (operator %s not defined on %s)",
+		node.Op, node.Right, node.Op, right.Type())
+}
+
+func (in *Interpreter) evalInfixExpr(pos token.Pos, op token.Token, left, right object.Object) (object.Object, error) {
+	switch op {
+	case token.EQ:
+		return object.NewBool(object.Equal(left, right)), nil
+	case token.NEQ:
+		return object.NewBool(!object.Equal(left, right)), nil
+	}
+
+	switch left := left.(type) {
+	case *object.Int, *object.BigInt:
+		a, aok := left.(*object.Int)
+		b, bok := right.(*object.Int)
+		if aok && bok && !(in.BigInts && overflows(op, a.Value, b.Value)) {
+			return evalIntInfixExpr(pos, op, a.Value, b.Value)
+		}
+		x, _ := toBig(left)
+		if y, ok := toBig(right); ok {
+			return evalBigInfixExpr(pos, op, x, y)
+		}
+	case *object.String:
+		if right, ok := right.(*object.String); ok {
+			return evalStringInfixExpr(pos, op, left.Value, right.Value)
+		}
+	}
+
+	return nil, errorf(pos, ErrType, "invalid operation: operator %s not defined on %s and %s",
+		op, left.Type(), right.Type())
+}
+
+// evalIntInfixExpr defines integer arithmetic. Ints are 64-bit two's
+// complement and every operation wraps around on overflow, including
+// -x and x / -1 for the smallest int. Division truncates toward zero and
+// the result of % has the sign of the dividend, so a == a/b*b + a%b.
+// Dividing by zero is an error. Shift counts must not be negative; a
+// count of 64 or more shifts every bit out, leaving 0 for << and for >>
+// of a non-negative value, and -1 for >> of a negative one.
+func evalIntInfixExpr(pos token.Pos, op token.Token, a, b int64) (object.Object, error) {
+	switch op {
+	case token.ADD:
+		return &object.Int{Value: a + b}, nil
+	case token.SUB:
+		return &object.Int{Value: a - b}, nil
+	case token.MUL:
+		return &object.Int{Value: a * b}, nil
+	case token.DIV, token.MOD:
+		if b == 0 {
+			return nil, errorf(pos, ErrDivisionByZero, "integer division by zero")
+		}
+		if op == token.DIV {
+			return &object.Int{Value: a / b}, nil
+		}
+		return &object.Int{Value: a % b}, nil
+	case token.AND:
+		return &object.Int{Value: a & b}, nil
+	case token.OR:
+		return &object.Int{Value: a | b}, nil
+	case token.XOR:
+		return &object.Int{Value: a ^ b}, nil
+	case token.LSHIFT, token.RSHIFT:
+		if b < 0 {
+			return nil, errorf(pos, ErrType, "negative shift count %d", b)
+		}
+		if op == token.LSHIFT {
+			return &object.Int{Value: a << b}, nil
+		}
+		return &object.Int{Value: a >> b}, nil
+	case token.DOTDOT, token.DOTDOT_EQ:
+		return &object.Range{Start: a, End: b, Inclusive: op == token.DOTDOT_EQ}, nil
+	case token.LT:
+		return object.NewBool(a < b), nil
+	case token.LTE:
+		return object.NewBool(a <= b), nil
+	case token.GT:
+		return object.NewBool(a > b), nil
+	case token.GTE:
+		return object.NewBool(a >= b), nil
+	}
+
+	return nil, errorf(pos, ErrType, "invalid operation: operator %s not defined on int", op)
+}
+
+func evalStringInfixExpr(pos token.Pos, op token.Token, a, b string) (object.Object, error) {
+	switch op {
+	case token.ADD:
+		return &object.String{Value: a + b}, nil
+	case token.LT:
+		return object.NewBool(a < b), nil
+	case token.LTE:
+		return object.NewBool(a <= b), nil
+	case token.GT:
+		return object.NewBool(a > b), nil
+	case token.GTE:
+		return object.NewBool(a >= b), nil
+	}
+
+	return nil, errorf(pos, ErrType, "invalid operation: operator %s not defined on str", op)
+}
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"oasis/ast"
+	"oasis/object"
+	"oasis/token"
+)
+
+// arity returns the least and greatest number of arguments lit accepts.
+// max is -1 for variadic functions.
+func arity(lit *ast.FuncLit) (min, max int) {
+	for _, param := range lit.Params {
+		if param.Default == nil {
+			min++
+		}
+	}
+	if lit.Rest != nil {
+		return min, -1
+	}
+	return min, len(lit.Params)
+}
+
+// checkArity reports a call of fn with n arguments at pos that fn does
+// not accept.
+func checkArity(pos token.Pos, fn *object.Func, n int) error {
+	min, max := arity(fn.Lit)
+	switch {
+	case n >= min && (max < 0 || n <= max):
+		return nil
+	case max < 0:
+		return errorf(pos, ErrArgCount, "wrong number of arguments to %s: want at least %d, got %d", funcName(fn), min, n)
+	case min < max:
+		return errorf(pos, ErrArgCount, "wrong number of arguments to %s: want %d to %d, got %d", funcName(fn), min, max, n)
+	}
+	return errorf(pos, ErrArgCount, "wrong number of arguments to %s: want %d, got %d", funcName(fn), min, n)
+}
+
+// bindParams binds the parameters of fn to args, the arguments of the
+// call at pos, in env. A missing or nil argument takes the default value
+// of its parameter, which is evaluated in env so that it can refer to the
+// parameters before it. Arguments beyond the parameters go to the
+// variadic parameter as an array.
+func (in *Interpreter) bindParams(pos token.Pos, fn *object.Func, args []object.Object, env *object.Env) error {
+	for i, param := range fn.Lit.Params {
+		var val object.Object
+		if i < len(args) && args[i] != nil {
+			val = args[i]
+		} else if param.Default != nil {
+			var err error
+			if val, err = in.eval(param.Default, env); err != nil {
+				return err
+			}
+		} else {
+			return errorf(pos, ErrArgCount, "missing argument for parameter %s in call to %s", param.Pattern, funcName(fn))
+		}
+		if err := in.bind(param.Pattern, val, env); err != nil {
+			return err
+		}
+	}
+
+	if rest := fn.Lit.Rest; rest != nil && rest.Name != nil {
+		var elems []object.Object
+		if len(args) > len(fn.Lit.Params) {
+			elems = append(elems, args[len(fn.Lit.Params):]...)
+		}
+		arr := &object.Array{Elems: elems}
+		if err := in.track(rest.Ellipsis, arr); err != nil {
+			return err
+		}
+		env.Define(rest.Name.Value, arr)
+	}
+	return nil
+}
+
+// evalArgs evaluates the arguments of a call, expanding spread arrays and
+// tuples into their elements.
+func (in *Interpreter) evalArgs(exprs []ast.Expr, env *object.Env) ([]object.Object, error) {
+	args := make([]object.Object, 0, len(exprs))
+	for _, expr := range exprs {
+		spread, ok := expr.(*ast.SpreadExpr)
+		if !ok {
+			arg, err := in.eval(expr, env)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			continue
+		}
+
+		val, err := in.eval(spread.X, env)
+		if err != nil {
+			return nil, err
+		}
+		switch val := val.(type) {
+		case *object.Array:
+			args = append(args, val.Elems...)
+		case *object.Tuple:
+			args = append(args, val.Elems...)
+		default:
+			err := errorf(spread.X.Pos(), ErrType, "cannot spread %s (type %s) into arguments", spread.X, val.Type())
+			err.Expr = spread.X.String()
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// evalNamedArgs evaluates the named arguments of call, a call of fn, and
+// returns args with each of them at the index of its parameter. Parameters
+// given neither by position nor by name are left nil, for bindParams to
+// fill in with their defaults.
+func (in *Interpreter) evalNamedArgs(call *ast.CallExpr, fn object.Object, args []object.Object, env *object.Env) ([]object.Object, error) {
+	var f *object.Func
+	switch fn := fn.(type) {
+	case *object.Func:
+		f = fn
+	case *object.Builtin:
+		return nil, errorf(call.Named[0].Pos(), ErrArgName, "cannot pass named arguments to builtin %s", fn.Name)
+	default:
+		// Leave it to apply to report that fn cannot be called.
+		return args, nil
+	}
+
+	params := f.Lit.Params
+	if len(args) < len(params) {
+		args = append(args, make([]object.Object, len(params)-len(args))...)
+	}
+
+	for _, arg := range call.Named {
+		i := paramIndex(f.Lit, arg.Name.Value)
+		if i < 0 {
+			return nil, errorf(arg.Name.NamePos, ErrArgName, "unknown parameter %s in call to %s", arg.Name, funcName(f))
+		}
+		if args[i] != nil {
+			return nil, errorf(arg.Name.NamePos, ErrArgName, "parameter %s of %s is already given by position", arg.Name, funcName(f))
+		}
+
+		val, err := in.eval(arg.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return args, nil
+}
+
+// paramIndex returns the index of the parameter of lit called name, or -1
+// if there is none. Destructured parameters have no name.
+func paramIndex(lit *ast.FuncLit, name string) int {
+	for i, param := range lit.Params {
+		if bind, ok := param.Pattern.(*ast.BindPattern); ok && bind.Name.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// argPos returns the position of the argument at index i of call, or of
+// the call itself if an earlier spread makes it unknown.
+func argPos(call *ast.CallExpr, i int) token.Pos {
+	for j, arg := range call.Args {
+		if _, ok := arg.(*ast.SpreadExpr); ok {
+			break
+		}
+		if j == i {
+			return arg.Pos()
+		}
+	}
+	return call.Pos()
+}
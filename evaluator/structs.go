@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"oasis/ast"
+	"oasis/object"
+)
+
+// evalStructStmt binds the name of a struct declaration to its type.
+func (in *Interpreter) evalStructStmt(node *ast.StructStmt, env *object.Env) (object.Object, error) {
+	def := &object.StructType{Name: node.Name.Value, Fields: make([]string, len(node.Fields))}
+	for i, field := range node.Fields {
+		def.Fields[i] = field.Name.Value
+	}
+	env.Define(def.Name, def)
+	return object.Null, nil
+}
+
+// evalStructLit creates a struct, whose fields must all be given.
+func (in *Interpreter) evalStructLit(node *ast.StructLit, env *object.Env) (object.Object, error) {
+	typ, err := in.eval(node.Type, env)
+	if err != nil {
+		return nil, err
+	}
+	def, ok := typ.(*object.StructType)
+	if !ok {
+		return nil, errorf(node.Type.Pos(), ErrType, "%s (type %s) is not a struct type", node.Type, typ.Type())
+	}
+
+	s := &object.Struct{Def: def, Fields: make([]object.Object, len(def.Fields))}
+	for _, field := range node.Fields {
+		i := def.Field(field.Name.Value)
+		if i < 0 {
+			return nil, errorf(field.Name.Pos(), ErrField, "unknown field %s in %s literal", field.Name, def.Name)
+		}
+		if s.Fields[i], err = in.eval(field.Value, env); err != nil {
+			return nil, err
+		}
+	}
+	for i, val := range s.Fields {
+		if val == nil {
+			return nil, errorf(node.Lbrace, ErrField, "missing field %s in %s literal", def.Fields[i], def.Name)
+		}
+	}
+
+	return s, in.track(node.Lbrace, s)
+}
+
+// field returns the index of the field node.Sel of s, the value of
+// node.X.
+func field(node *ast.SelectorExpr, s *object.Struct) (int, error) {
+	i := s.Def.Field(node.Sel.Value)
+	if i < 0 {
+		return 0, errorf(node.Sel.Pos(), ErrField, "%s (type %s) has no field %s", node.X, s.Def.Name, node.Sel)
+	}
+	return i, nil
+}
+
+// evalFieldAssign evaluates x.f = value and the compound assignments to
+// x.f.
+func (in *Interpreter) evalFieldAssign(node *ast.InfixExpr, sel *ast.SelectorExpr, env *object.Env) (object.Object, error) {
+	x, err := in.eval(sel.X, env)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := x.(*object.Struct)
+	if !ok {
+		return nil, errorf(node.OpPos, ErrType, "cannot assign to field %s of %s (type %s)", sel.Sel, sel.X, x.Type())
+	}
+	i, err := field(sel, s)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := in.eval(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := assignOps[node.Op]; ok {
+		if val, err = in.evalInfixExpr(node.OpPos, op, s.Fields[i], val); err != nil {
+			return nil, err
+		}
+		if err := in.track(node.OpPos, val); err != nil {
+			return nil, err
+		}
+	}
+
+	s.Fields[i] = val
+	return val, nil
+}
@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"oasis/ast"
+	"oasis/object"
+)
+
+// tailCall is returned instead of calling fn when the call is the last
+// thing its function does. The apply of that function then runs fn in
+// its place, so tail recursion does not grow the Go stack.
+type tailCall struct {
+	call *ast.CallExpr
+	fn   *object.Func
+	args []object.Object
+}
+
+func (s *tailCall) Error() string { return "tail call outside function" }
+
+// markTailCalls records the calls in tail position in the body of lit:
+// the value of a return, and the final expression of the body, looking
+// through blocks, both branches of an if and the arms of a match.
+func (in *Interpreter) markTailCalls(lit *ast.FuncLit) {
+	if in.analyzed[lit] {
+		return
+	}
+	in.analyzed[lit] = true
+
+	in.markTail(lit.Body)
+	ast.Inspect(lit.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if node.Value != nil {
+				in.markTail(node.Value)
+			}
+		}
+		return true
+	})
+}
+
+func (in *Interpreter) markTail(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case *ast.CallExpr:
+		in.tailCalls[expr] = true
+	case *ast.BlockExpr:
+		if len(expr.Stmts) == 0 {
+			return
+		}
+		if stmt, ok := expr.Stmts[len(expr.Stmts)-1].(*ast.ExprStmt); ok {
+			in.markTail(stmt.Expr)
+		}
+	case *ast.IfExpr:
+		in.markTail(expr.TrueCase)
+		if expr.FalseCase != nil {
+			in.markTail(expr.FalseCase)
+		}
+	case *ast.MatchExpr:
+		for _, arm := range expr.Arms {
+			in.markTail(arm.Body)
+		}
+	}
+}
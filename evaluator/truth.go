@@ -0,0 +1,38 @@
+package evaluator
+
+import "oasis/object"
+
+// Truthiness selects how conditions and the logical operators treat
+// values that are not booleans.
+type Truthiness int
+
+const (
+	// Strict requires booleans. Any other value used as an if or while
+	// condition, or as an operand of &&, || or !, is a type error.
+	Strict Truthiness = iota
+	// Truthy accepts any value. false, null, 0, "" and empty arrays and
+	// maps are false and everything else is true. && and || return the
+	// operand that decided the result rather than a boolean, so
+	// name || "anonymous" picks the first truthy value.
+	Truthy
+)
+
+func truthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.NullValue:
+		return false
+	case *object.Bool:
+		return obj.Value
+	case *object.Int:
+		return obj.Value != 0
+	case *object.BigInt:
+		return obj.Value.Sign() != 0
+	case *object.String:
+		return obj.Value != ""
+	case *object.Array:
+		return len(obj.Elems) > 0
+	case *object.Map:
+		return obj.Len() > 0
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+// Package fold evaluates constant integer expressions before a program
+// runs.
+package fold
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"oasis/ast"
+	"oasis/diag"
+	"oasis/token"
+)
+
+var (
+	minInt = big.NewInt(math.MinInt64)
+	maxInt = big.NewInt(math.MaxInt64)
+)
+
+// maxBigShift is the largest count the evaluator shifts big ints left
+// by; larger shifts are left to fail at run time.
+const maxBigShift = 1 << 24
+
+type folder struct {
+	bigInts bool
+	diags   []*diag.Diagnostic
+}
+
+// Program replaces every prefix and infix expression in prog whose
+// operands are all int literals by the literal of its value. With
+// bigInts set, constants have any size, as ints do at run time.
+// Otherwise, unlike at run time, constant arithmetic does not wrap
+// around: overflow is an error, and literals too large for an int are
+// left alone. Division by zero and negative shift counts are errors in
+// either mode.
+func Program(prog *ast.Program, bigInts bool) []*diag.Diagnostic {
+	f := &folder{bigInts: bigInts}
+	f.node(prog)
+	return f.diags
+}
+
+// node folds the expressions below n.
+func (f *folder) node(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.Program:
+		for _, stmt := range n.Stmts {
+			f.node(stmt)
+		}
+	case *ast.ExprStmt:
+		f.expr(&n.Expr)
+	case *ast.LetStmt:
+		f.expr(&n.Value)
+	case *ast.ConstStmt:
+		f.expr(&n.Value)
+	case *ast.ReturnStmt:
+		if n.Value != nil {
+			f.expr(&n.Value)
+		}
+	case *ast.BreakStmt:
+		if n.Value != nil {
+			f.expr(&n.Value)
+		}
+	case *ast.SelectorExpr:
+		f.expr(&n.X)
+	case *ast.CallExpr:
+		f.expr(&n.Func)
+		for i := range n.Args {
+			f.expr(&n.Args[i])
+		}
+		for _, arg := range n.Named {
+			f.expr(&arg.Value)
+		}
+	case *ast.BlockExpr:
+		for _, stmt := range n.Stmts {
+			f.node(stmt)
+		}
+	case *ast.IfExpr:
+		f.expr(&n.Condition)
+		f.expr(&n.TrueCase)
+		if n.FalseCase != nil {
+			f.expr(&n.FalseCase)
+		}
+	case *ast.WhileExpr:
+		f.expr(&n.Condition)
+		f.expr(&n.Body)
+	case *ast.ForExpr:
+		f.expr(&n.Iter)
+		f.expr(&n.Body)
+	case *ast.MatchExpr:
+		f.expr(&n.Subject)
+		for _, arm := range n.Arms {
+			if arm.Guard != nil {
+				f.expr(&arm.Guard)
+			}
+			f.expr(&arm.Body)
+		}
+	case *ast.SpreadExpr:
+		f.expr(&n.X)
+	case *ast.StructLit:
+		for _, field := range n.Fields {
+			f.expr(&field.Value)
+		}
+	case *ast.TupleLit:
+		for i := range n.Elems {
+			f.expr(&n.Elems[i])
+		}
+	case *ast.ArrayLit:
+		for i := range n.Elems {
+			f.expr(&n.Elems[i])
+		}
+	case *ast.FuncLit:
+		for _, param := range n.Params {
+			if param.Default != nil {
+				f.expr(&param.Default)
+			}
+		}
+		f.expr(&n.Body)
+	}
+}
+
+// expr folds *e in place and returns its value if it is constant.
+func (f *folder) expr(e *ast.Expr) (*big.Int, bool) {
+	switch n := (*e).(type) {
+	case *ast.IntLit:
+		v, ok := new(big.Int).SetString(n.Value, 10)
+		return v, ok && (f.bigInts || fits(v))
+
+	case *ast.PrefixExpr:
+		// The magnitude of the smallest int does not fit in an int by
+		// itself, so -9223372036854775808 is folded as one literal.
+		if lit, ok := n.Right.(*ast.IntLit); ok && n.Op == token.SUB {
+			if v, ok := new(big.Int).SetString("-"+lit.Value, 10); ok && (f.bigInts || fits(v)) {
+				*e = &ast.IntLit{ValuePos: n.OpPos, Value: v.String()}
+				return v, true
+			}
+		}
+
+		x, ok := f.expr(&n.Right)
+		if !ok {
+			return nil, false
+		}
+
+		var v *big.Int
+		switch n.Op {
+		case token.SUB:
+			v = new(big.Int).Neg(x)
+		case token.TILDE:
+			v = new(big.Int).Not(x)
+		default:
+			return nil, false
+		}
+		if !f.bigInts && !fits(v) {
+			f.errorf(n.OpPos, 1, "constant %s(%s) overflows int", n.Op, x)
+			return nil, false
+		}
+		*e = &ast.IntLit{ValuePos: n.OpPos, Value: v.String()}
+		return v, true
+
+	case *ast.InfixExpr:
+		if n.Op.IsAssignment() {
+			f.expr(&n.Right)
+			return nil, false
+		}
+
+		x, okx := f.expr(&n.Left)
+		y, oky := f.expr(&n.Right)
+		if !okx || !oky {
+			return nil, false
+		}
+
+		v, ok := f.infix(n, x, y)
+		if !ok {
+			return nil, false
+		}
+		*e = &ast.IntLit{ValuePos: n.Pos(), Value: v.String()}
+		return v, true
+	}
+
+	f.node(*e)
+	return nil, false
+}
+
+// infix returns x op y for an operator with int operands, reporting
+// overflow, division by zero and negative shift counts. ok is false if
+// the expression has no constant value.
+func (f *folder) infix(n *ast.InfixExpr, x, y *big.Int) (v *big.Int, ok bool) {
+	v = new(big.Int)
+	switch n.Op {
+	case token.ADD:
+		v.Add(x, y)
+	case token.SUB:
+		v.Sub(x, y)
+	case token.MUL:
+		v.Mul(x, y)
+	case token.DIV, token.MOD:
+		if y.Sign() == 0 {
+			f.errorf(n.OpPos, 1, "integer division by zero")
+			return nil, false
+		}
+		if n.Op == token.DIV {
+			v.Quo(x, y)
+		} else {
+			v.Rem(x, y)
+		}
+	case token.AND:
+		v.And(x, y)
+	case token.OR:
+		v.Or(x, y)
+	case token.XOR:
+		v.Xor(x, y)
+	case token.LSHIFT, token.RSHIFT:
+		if y.Sign() < 0 {
+			f.errorf(n.OpPos, diag.Span(n.Op.String()), "negative shift count %s", y)
+			return nil, false
+		}
+		if n.Op == token.RSHIFT {
+			// Shifting out every bit leaves 0 or -1.
+			shift := uint(x.BitLen())
+			if y.IsUint64() && y.Uint64() < uint64(shift) {
+				shift = uint(y.Uint64())
+			}
+			v.Rsh(x, shift)
+			break
+		}
+		if x.Sign() == 0 {
+			break
+		}
+		// Any shift of a nonzero int by 64 or more overflows an int.
+		shift := uint64(64)
+		if y.IsUint64() && y.Uint64() < shift {
+			shift = y.Uint64()
+		}
+		if f.bigInts {
+			if !y.IsUint64() || y.Uint64() > maxBigShift {
+				return nil, false
+			}
+			shift = y.Uint64()
+		}
+		v.Lsh(x, uint(shift))
+	default:
+		return nil, false
+	}
+
+	if !f.bigInts && !fits(v) {
+		f.errorf(n.OpPos, diag.Span(n.Op.String()), "constant %s %s %s overflows int", x, n.Op, y)
+		return nil, false
+	}
+	return v, true
+}
+
+func (f *folder) errorf(pos token.Pos, n int, format string, args ...any) {
+	f.diags = append(f.diags, &diag.Diagnostic{Pos: pos, Len: n, Msg: fmt.Sprintf(format, args...)})
+}
+
+func fits(v *big.Int) bool {
+	return v.Cmp(minInt) >= 0 && v.Cmp(maxInt) <= 0
+}
@@ -0,0 +1,113 @@
+package fold
+
+import (
+	"oasis/lexer"
+	"oasis/parser"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"1 + 2 * 3", "7;"},
+		{"-(4 - 10) / 4", "1;"},
+		{"-7 % 3", "-1;"},
+		{"~0 << 4 | 3", "-13;"},
+		{"1 << 62 >> 70", "0;"},
+		{"-1 >> 100", "-1;"},
+		{"0 << 100", "0;"},
+		{"-9223372036854775808", "-9223372036854775808;"},
+		{"x + 2 * 3", "(x + 6);"},
+		{"f(1 + 1, 2 * x)", "f(2, (2 * x), );"},
+		{"x = 1 + 1", "(x = 2);"},
+		{"1 < 2", "(1 < 2);"},
+		{"0..10 * 2", "(0 .. 20);"},
+		{"if x { 2 - 1 } else { 0 }", "if x { 1; } else { 0; };"},
+		{"let f = func(n) { return n * (60 * 60) }", "let f = func(n, ) { return (n * 3600); };"},
+		{"match x { 1 => 2 + 2, n if n > 1 + 1 => n }", "match x { 1 => 4, n if (n > 2) => n, };"},
+		{"99999999999999999999 + 1", "(99999999999999999999 + 1);"},
+		{"x / 0", "(x / 0);"},
+		{"1 << 62", "4611686018427387904;"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: parse failed", i)
+		}
+
+		if diags := Program(program, false); len(diags) > 0 {
+			t.Fatalf("tests[%d]: unexpected errors: %v", i, diags)
+		}
+		if out := program.Stmts[0].String(); out != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, out)
+		}
+	}
+}
+
+func TestBigInts(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808;"},
+		{"99999999999999999999 + 1", "100000000000000000000;"},
+		{"-(-9223372036854775808)", "9223372036854775808;"},
+		{"1 << 64", "18446744073709551616;"},
+		{"-(1 << 70) >> 100", "-1;"},
+		{"2 * 3", "6;"},
+		{"1 << 99999999", "(1 << 99999999);"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: parse failed", i)
+		}
+
+		if diags := Program(program, true); len(diags) > 0 {
+			t.Fatalf("tests[%d]: unexpected errors: %v", i, diags)
+		}
+		if out := program.Stmts[0].String(); out != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, out)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		errs  []string
+	}{
+		{"9223372036854775807 + 1", []string{"1:21: constant 9223372036854775807 + 1 overflows int"}},
+		{"const c = 3037000500 * 3037000500", []string{"1:22: constant 3037000500 * 3037000500 overflows int"}},
+		{"-(-9223372036854775808)", []string{"1:1: constant -(-9223372036854775808) overflows int"}},
+		{"1 << 63", []string{"1:3: constant 1 << 63 overflows int"}},
+		{"3 << 100", []string{"1:3: constant 3 << 100 overflows int"}},
+		{"-9223372036854775808 / -1", []string{"1:22: constant -9223372036854775808 / -1 overflows int"}},
+		{"10 / (5 - 5)", []string{"1:4: integer division by zero"}},
+		{"x + 1 % 0", []string{"1:7: integer division by zero"}},
+		{"1 >> -1", []string{"1:3: negative shift count -1"}},
+		{"f(1 / 0, 2 % 0)", []string{"1:5: integer division by zero", "1:12: integer division by zero"}},
+		{"(1 / 0) * 2", []string{"1:4: integer division by zero"}},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: parse failed", i)
+		}
+
+		diags := Program(program, false)
+		if len(diags) != len(tt.errs) {
+			t.Fatalf("tests[%d]: expected %d errors, got %v", i, len(tt.errs), diags)
+		}
+		for j, d := range diags {
+			if d.Error() != tt.errs[j] {
+				t.Fatalf("tests[%d]: expected %q, got %q", i, tt.errs[j], d)
+			}
+		}
+	}
+}
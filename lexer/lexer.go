@@ -1,28 +1,86 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"oasis/token"
+	"unicode"
+	"unicode/utf8"
 )
 
+type Error struct {
+	Pos token.Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+const readerBufSize = 64 * 1024
+
 type Lexer struct {
+	file  string
 	input string
 
+	r    *bufio.Reader
+	rerr error
+	eof  bool
+	buf  []byte
+	bad  byte
+
 	pos     int
 	readPos int
 
-	ch         byte
+	line int
+	col  int
+
+	ch         rune
+	size       int
 	insertSemi bool
+
+	tokPos token.Pos
+	err    error
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.advance()
 	return l
 }
 
+// NewFile is like New, but the positions it reports name file.
+func NewFile(file, input string) *Lexer {
+	l := New(input)
+	l.file = file
+	return l
+}
+
+// NewReader returns a lexer that reads its input from r through a fixed
+// size buffer, so the input never has to be held in memory at once. The
+// tokens, literals and positions it produces are identical to New.
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{r: bufio.NewReaderSize(r, readerBufSize), line: 1}
+	l.advance()
+	return l
+}
+
+// Pos returns the position of the last token returned by NextToken.
+func (l *Lexer) Pos() token.Pos {
+	return l.tokPos
+}
+
+// Error returns the reason for the last ILLEGAL token, or nil.
+func (l *Lexer) Error() error {
+	return l.err
+}
+
 func (l *Lexer) NextToken() (token.Token, string) {
 	l.skipWhitespace()
 
+	l.tokPos = token.Pos{File: l.file, Line: l.line, Col: l.col}
+
 	if l.insertSemi && (l.ch == 0 || l.ch == '\n' || l.ch == '}') {
 		l.insertSemi = false
 		return token.SEMI, ";"
@@ -33,6 +91,11 @@ func (l *Lexer) NextToken() (token.Token, string) {
 	l.insertSemi = false
 	switch l.ch {
 	case 0:
+		if l.rerr != nil {
+			l.err = &Error{Pos: l.tokPos, Msg: l.rerr.Error()}
+			l.rerr = nil
+			return token.ILLEGAL, ""
+		}
 		tok = token.EOF
 		lit = ""
 	case '=':
@@ -40,6 +103,10 @@ func (l *Lexer) NextToken() (token.Token, string) {
 			l.advance()
 			tok = token.EQ
 			lit = "=="
+		} else if l.peek() == '>' {
+			l.advance()
+			tok = token.FATARROW
+			lit = "=>"
 		} else {
 			tok = token.ASSIGN
 			lit = "="
@@ -58,6 +125,10 @@ func (l *Lexer) NextToken() (token.Token, string) {
 			l.advance()
 			tok = token.SUB_ASSIGN
 			lit = "-="
+		} else if l.peek() == '>' {
+			l.advance()
+			tok = token.ARROW
+			lit = "->"
 		} else {
 			tok = token.SUB
 			lit = "-"
@@ -174,9 +245,34 @@ func (l *Lexer) NextToken() (token.Token, string) {
 			tok = token.NOT
 			lit = "!"
 		}
+	case '.':
+		if l.peek() != '.' {
+			tok = token.DOT
+			lit = "."
+			break
+		}
+		l.advance()
+		if l.peek() == '=' {
+			l.advance()
+			tok = token.DOTDOT_EQ
+			lit = "..="
+		} else if l.peek() == '.' {
+			l.advance()
+			tok = token.ELLIPSIS
+			lit = "..."
+		} else {
+			tok = token.DOTDOT
+			lit = ".."
+		}
+	case '"':
+		l.insertSemi = true
+		return l.readString()
 	case ',':
 		tok = token.COMMA
 		lit = ","
+	case ':':
+		tok = token.COLON
+		lit = ":"
 	case ';':
 		tok = token.SEMI
 		lit = ";"
@@ -194,19 +290,26 @@ func (l *Lexer) NextToken() (token.Token, string) {
 		l.insertSemi = true
 		tok = token.RBRACE
 		lit = "}"
+	case '[':
+		tok = token.LBRACKET
+		lit = "["
+	case ']':
+		l.insertSemi = true
+		tok = token.RBRACKET
+		lit = "]"
 	default:
 		if isLetter(l.ch) {
 			l.insertSemi = true
 			lit = l.readIdent()
 			tok = token.LookupIdent(lit)
 			return tok, lit
-		} else if isDigit(l.ch) {
+		} else if isDecimal(l.ch) {
 			l.insertSemi = true
 			tok = token.INT
 			lit = l.readNumber()
 			return tok, lit
 		} else {
-			return token.ILLEGAL, string(l.ch)
+			return l.illegal()
 		}
 	}
 
@@ -215,19 +318,129 @@ func (l *Lexer) NextToken() (token.Token, string) {
 	return tok, lit
 }
 
+// readString reads a double-quoted string literal and returns it with
+// its quotes and escapes as written. The escapes are \n, \t, \r, \\
+// and \". A string may not span lines.
+func (l *Lexer) readString() (token.Token, string) {
+	var buf []byte
+	buf = append(buf, '"')
+	l.advance()
+
+	for l.ch != '"' {
+		switch {
+		case l.ch == '\n' || l.ch == 0 && l.size == 0:
+			l.err = &Error{Pos: l.tokPos, Msg: "string literal not terminated"}
+			return token.ILLEGAL, string(buf)
+		case l.ch == utf8.RuneError && l.size == 1:
+			pos := token.Pos{File: l.file, Line: l.line, Col: l.col}
+			tok, lit := l.illegal()
+			l.err.(*Error).Pos = pos
+			l.skipString()
+			return tok, lit
+		case l.ch == '\\':
+			pos := token.Pos{File: l.file, Line: l.line, Col: l.col}
+			buf = append(buf, '\\')
+			l.advance()
+			switch l.ch {
+			case 'n', 't', 'r', '\\', '"':
+			case '\n', 0:
+				continue
+			default:
+				l.err = &Error{Pos: pos, Msg: fmt.Sprintf("unknown escape sequence \\%c", l.ch)}
+				l.skipString()
+				return token.ILLEGAL, string(buf)
+			}
+		}
+		buf = utf8.AppendRune(buf, l.ch)
+		l.advance()
+	}
+
+	l.advance()
+	return token.STRING, string(append(buf, '"'))
+}
+
+// skipString moves past the rest of a malformed string literal.
+func (l *Lexer) skipString() {
+	for l.ch != '"' && l.ch != '\n' && !(l.ch == 0 && l.size == 0) {
+		if l.ch == '\\' {
+			l.advance()
+		}
+		l.advance()
+	}
+	if l.ch == '"' {
+		l.advance()
+	}
+}
+
+func (l *Lexer) illegal() (token.Token, string) {
+	var lit string
+	if l.ch == utf8.RuneError && l.size == 1 {
+		if l.r != nil {
+			lit = string([]byte{l.bad})
+		} else {
+			lit = l.input[l.pos:l.readPos]
+		}
+		l.err = &Error{Pos: l.tokPos, Msg: fmt.Sprintf("invalid UTF-8 encoding %q", lit)}
+	} else {
+		lit = string(l.ch)
+		l.err = &Error{Pos: l.tokPos, Msg: fmt.Sprintf("illegal character %#U", l.ch)}
+	}
+	l.advance()
+	return token.ILLEGAL, lit
+}
+
 func (l *Lexer) advance() {
-	if l.readPos < len(l.input) {
-		l.ch = l.input[l.readPos]
+	if l.ch == '\n' {
+		l.line++
+		l.col = 0
+	}
+	if l.size > 0 || l.col == 0 {
+		l.col++
+	}
+	if l.r != nil {
+		l.ch, l.size = l.readRune()
+	} else if l.readPos < len(l.input) {
+		l.ch, l.size = utf8.DecodeRuneInString(l.input[l.readPos:])
 	} else {
-		l.ch = 0
+		l.ch, l.size = 0, 0
 	}
 	l.pos = l.readPos
-	l.readPos++
+	l.readPos += l.size
 }
 
-func (l *Lexer) peek() byte {
+func (l *Lexer) readRune() (rune, int) {
+	if l.eof {
+		return 0, 0
+	}
+
+	ch, size, err := l.r.ReadRune()
+	if err != nil {
+		l.eof = true
+		if err != io.EOF {
+			l.rerr = err
+		}
+		return 0, 0
+	}
+
+	if ch == utf8.RuneError && size == 1 {
+		l.r.UnreadRune()
+		l.bad, _ = l.r.ReadByte()
+	}
+	return ch, size
+}
+
+func (l *Lexer) peek() rune {
+	if l.r != nil {
+		buf, _ := l.r.Peek(utf8.UTFMax)
+		if len(buf) == 0 {
+			return 0
+		}
+		ch, _ := utf8.DecodeRune(buf)
+		return ch
+	}
 	if l.readPos < len(l.input) {
-		return l.input[l.readPos]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPos:])
+		return ch
 	}
 	return 0
 }
@@ -239,25 +452,40 @@ func (l *Lexer) skipWhitespace() {
 }
 
 func (l *Lexer) readIdent() string {
-	pos := l.pos
-	for isLetter(l.ch) || isDigit(l.ch) {
-		l.advance()
-	}
-	return l.input[pos:l.pos]
+	return l.readWhile(func(ch rune) bool {
+		return isLetter(ch) || isDigit(ch)
+	})
 }
 
 func (l *Lexer) readNumber() string {
-	pos := l.pos
-	for isDigit(l.ch) {
+	return l.readWhile(isDecimal)
+}
+
+func (l *Lexer) readWhile(f func(rune) bool) string {
+	if l.r == nil {
+		pos := l.pos
+		for f(l.ch) {
+			l.advance()
+		}
+		return l.input[pos:l.pos]
+	}
+
+	l.buf = l.buf[:0]
+	for f(l.ch) {
+		l.buf = utf8.AppendRune(l.buf, l.ch)
 		l.advance()
 	}
-	return l.input[pos:l.pos]
+	return string(l.buf)
+}
+
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isDigit(ch rune) bool {
+	return isDecimal(ch) || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDecimal(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
@@ -1,17 +1,23 @@
 package lexer
 
 import (
+	"errors"
+	"io"
 	"oasis/token"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
 	input := `a 10
 = + - * / % & | ^ << >> ~ += -= *= /= %= &= |= ^= <<= >>=
 && || ! == != < <= > >=
-, ;
-() {}
-let if else return func`
+.. ..= ... 0..10 => -> a.b
+"" "a\tb \"c\"" "→\\"
+, : ;
+() {} []
+import let mut const struct if else return func for in match`
 
 	tests := []struct {
 		tok token.Token
@@ -54,20 +60,48 @@ let if else return func`
 		{tok: token.GT, lit: ">"},
 		{tok: token.GTE, lit: ">="},
 
+		{tok: token.DOTDOT, lit: ".."},
+		{tok: token.DOTDOT_EQ, lit: "..="},
+		{tok: token.ELLIPSIS, lit: "..."},
+		{tok: token.INT, lit: "0"},
+		{tok: token.DOTDOT, lit: ".."},
+		{tok: token.INT, lit: "10"},
+		{tok: token.FATARROW, lit: "=>"},
+		{tok: token.ARROW, lit: "->"},
+		{tok: token.IDENT, lit: "a"},
+		{tok: token.DOT, lit: "."},
+		{tok: token.IDENT, lit: "b"},
+		{tok: token.SEMI, lit: ";"},
+
+		{tok: token.STRING, lit: `""`},
+		{tok: token.STRING, lit: `"a\tb \"c\""`},
+		{tok: token.STRING, lit: `"→\\"`},
+		{tok: token.SEMI, lit: ";"},
+
 		{tok: token.COMMA, lit: ","},
+		{tok: token.COLON, lit: ":"},
 		{tok: token.SEMI, lit: ";"},
 
 		{tok: token.LPAREN, lit: "("},
 		{tok: token.RPAREN, lit: ")"},
 		{tok: token.LBRACE, lit: "{"},
 		{tok: token.RBRACE, lit: "}"},
+		{tok: token.LBRACKET, lit: "["},
+		{tok: token.RBRACKET, lit: "]"},
 		{tok: token.SEMI, lit: ";"},
 
+		{tok: token.IMPORT, lit: "import"},
 		{tok: token.LET, lit: "let"},
+		{tok: token.MUT, lit: "mut"},
+		{tok: token.CONST, lit: "const"},
+		{tok: token.STRUCT, lit: "struct"},
 		{tok: token.IF, lit: "if"},
 		{tok: token.ELSE, lit: "else"},
 		{tok: token.RETURN, lit: "return"},
 		{tok: token.FUNC, lit: "func"},
+		{tok: token.FOR, lit: "for"},
+		{tok: token.IN, lit: "in"},
+		{tok: token.MATCH, lit: "match"},
 		{tok: token.SEMI, lit: ";"},
 	}
 
@@ -84,3 +118,279 @@ let if else return func`
 		}
 	}
 }
+
+func TestUnicode(t *testing.T) {
+	input := `café
+let σ = ναι2 + x١
+日本語`
+
+	tests := []struct {
+		tok token.Token
+		lit string
+	}{
+		{tok: token.IDENT, lit: "café"},
+		{tok: token.SEMI, lit: ";"},
+		{tok: token.LET, lit: "let"},
+		{tok: token.IDENT, lit: "σ"},
+		{tok: token.ASSIGN, lit: "="},
+		{tok: token.IDENT, lit: "ναι2"},
+		{tok: token.ADD, lit: "+"},
+		{tok: token.IDENT, lit: "x١"},
+		{tok: token.SEMI, lit: ";"},
+		{tok: token.IDENT, lit: "日本語"},
+		{tok: token.SEMI, lit: ";"},
+		{tok: token.EOF, lit: ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, lit := l.NextToken()
+
+		if tok != tt.tok {
+			t.Fatalf("tests[%d]: wrong token type: expected %q, got %q (%s)", i, tt.tok, tok, l.Error())
+		}
+
+		if lit != tt.lit {
+			t.Fatalf("tests[%d]: wrong literal: expected %q, got %q", i, tt.lit, lit)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := "é = 1\n\tαβ += x + ü\nfoo"
+
+	tests := []struct {
+		tok token.Token
+		pos token.Pos
+	}{
+		{tok: token.IDENT, pos: token.Pos{Line: 1, Col: 1}},
+		{tok: token.ASSIGN, pos: token.Pos{Line: 1, Col: 3}},
+		{tok: token.INT, pos: token.Pos{Line: 1, Col: 5}},
+		{tok: token.SEMI, pos: token.Pos{Line: 1, Col: 6}},
+		{tok: token.IDENT, pos: token.Pos{Line: 2, Col: 2}},
+		{tok: token.ADD_ASSIGN, pos: token.Pos{Line: 2, Col: 5}},
+		{tok: token.IDENT, pos: token.Pos{Line: 2, Col: 8}},
+		{tok: token.ADD, pos: token.Pos{Line: 2, Col: 10}},
+		{tok: token.IDENT, pos: token.Pos{Line: 2, Col: 12}},
+		{tok: token.SEMI, pos: token.Pos{Line: 2, Col: 13}},
+		{tok: token.IDENT, pos: token.Pos{Line: 3, Col: 1}},
+		{tok: token.SEMI, pos: token.Pos{Line: 3, Col: 4}},
+		{tok: token.EOF, pos: token.Pos{Line: 3, Col: 4}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, _ := l.NextToken()
+
+		if tok != tt.tok {
+			t.Fatalf("tests[%d]: wrong token type: expected %q, got %q", i, tt.tok, tok)
+		}
+
+		if l.Pos() != tt.pos {
+			t.Fatalf("tests[%d]: wrong position: expected %s, got %s", i, tt.pos, l.Pos())
+		}
+	}
+}
+
+func TestIllegal(t *testing.T) {
+	tests := []struct {
+		input string
+		lit   string
+		err   string
+	}{
+		{"a \xff", "\xff", `1:3: invalid UTF-8 encoding "\xff"`},
+		{"ü\n  \xc3(", "\xc3", `2:3: invalid UTF-8 encoding "\xc3"`},
+		{"x @", "@", "1:3: illegal character U+0040 '@'"},
+		{"1 → 2", "→", "1:3: illegal character U+2192 '→'"},
+		{`x = "abc`, `"abc`, "1:5: string literal not terminated"},
+		{"\"ab\ncd\"", `"ab`, "1:1: string literal not terminated"},
+		{`"a\qb"`, `"a\`, `1:3: unknown escape sequence \q`},
+		{"\"a\xffb\"", "\xff", `1:3: invalid UTF-8 encoding "\xff"`},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+
+		for {
+			tok, lit := l.NextToken()
+			if tok == token.EOF {
+				t.Fatalf("tests[%d]: expected %q, got %q", i, token.ILLEGAL, tok)
+			}
+			if tok != token.ILLEGAL {
+				continue
+			}
+
+			if lit != tt.lit {
+				t.Fatalf("tests[%d]: wrong literal: expected %q, got %q", i, tt.lit, lit)
+			}
+
+			if l.Error() == nil || l.Error().Error() != tt.err {
+				t.Fatalf("tests[%d]: wrong error: expected %q, got %v", i, tt.err, l.Error())
+			}
+			break
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		lit   string
+	}{
+		{"", `""`},
+		{"a\tb\r\n", `"a\tb\r\n"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{"日本", `"日本"`},
+	}
+
+	for i, tt := range tests {
+		if lit := token.Quote(tt.value); lit != tt.lit {
+			t.Fatalf("tests[%d]: expected %s, got %s", i, tt.lit, lit)
+		}
+
+		l := New(tt.lit)
+		tok, lit := l.NextToken()
+		if tok != token.STRING || lit != tt.lit {
+			t.Fatalf("tests[%d]: expected STRING %s, got %s %s", i, tt.lit, tok, lit)
+		}
+		if value := token.Unquote(lit); value != tt.value {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.value, value)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	inputs := []string{
+		"",
+		"let a = 10\nlet b = a << 2\nb >>= 1",
+		"if x { y } else { z }\nwhile true { break 10 }",
+		"func(α, β) { return α + β }(1, 2)",
+		"ü\n\xffa \xe6\x97 ok",
+		"x @ 日本語 \xc3",
+		"import \"a/b\"\nb.c(\"x\\ty\\\"\", \"\xff\", \"\\q\", \"open",
+	}
+
+	for i, input := range inputs {
+		want := New(input)
+		readers := []io.Reader{
+			strings.NewReader(input),
+			iotest.OneByteReader(strings.NewReader(input)),
+			iotest.HalfReader(strings.NewReader(input)),
+		}
+
+		for j, r := range readers {
+			got := NewReader(r)
+
+			for {
+				wantTok, wantLit := want.NextToken()
+				gotTok, gotLit := got.NextToken()
+
+				if gotTok != wantTok || gotLit != wantLit {
+					t.Fatalf("inputs[%d], readers[%d]: expected %q %q, got %q %q", i, j, wantTok, wantLit, gotTok, gotLit)
+				}
+
+				if got.Pos() != want.Pos() {
+					t.Fatalf("inputs[%d], readers[%d]: expected position %s, got %s", i, j, want.Pos(), got.Pos())
+				}
+
+				if gotTok == token.ILLEGAL && got.Error().Error() != want.Error().Error() {
+					t.Fatalf("inputs[%d], readers[%d]: expected error %q, got %q", i, j, want.Error(), got.Error())
+				}
+
+				if gotTok == token.EOF {
+					break
+				}
+			}
+
+			want = New(input)
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("a + b"), iotest.ErrReader(errors.New("disk on fire")))
+	l := NewReader(r)
+
+	for _, tok := range []token.Token{token.IDENT, token.ADD, token.IDENT, token.SEMI, token.ILLEGAL, token.EOF} {
+		got, _ := l.NextToken()
+		if got != tok {
+			t.Fatalf("wrong token type: expected %q, got %q", tok, got)
+		}
+	}
+
+	if l.Error() == nil || l.Error().Error() != "1:6: disk on fire" {
+		t.Fatalf("wrong error: got %v", l.Error())
+	}
+}
+
+func benchmarkInput() string {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		sb.WriteString("let fib = func(n) {\n\tif n < 2 { return n }\n\treturn fib(n - 1) + fib(n - 2) * naïve\n}\n")
+	}
+	return sb.String()
+}
+
+func BenchmarkNew(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := New(input)
+		for tok, _ := l.NextToken(); tok != token.EOF; tok, _ = l.NextToken() {
+		}
+	}
+}
+
+func BenchmarkNewReader(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := NewReader(strings.NewReader(input))
+		for tok, _ := l.NextToken(); tok != token.EOF; tok, _ = l.NextToken() {
+		}
+	}
+}
+
+func FuzzNextToken(f *testing.F) {
+	f.Add("a 10\n= + - * / % & | ^ << >> ~ += -= *= /= %= &= |= ^= <<= >>=")
+	f.Add("&& || ! == != < <= > >=\n, ;\n() {}\nlet if else return func")
+	f.Add("café\nlet σ = ναι2 + x١\n日本語")
+	f.Add("é = 1\n\tαβ += x + ü\nfoo")
+	f.Add("ü\n\xffa \xe6\x97 ok @")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input)
+		r := NewReader(strings.NewReader(input))
+
+		var last token.Pos
+		for n := 0; ; n++ {
+			if n > 2*len(input)+2 {
+				t.Fatalf("lexer did not reach EOF after %d tokens", n)
+			}
+
+			tok, lit := l.NextToken()
+			rtok, rlit := r.NextToken()
+			if tok != rtok || lit != rlit || l.Pos() != r.Pos() {
+				t.Fatalf("readers disagree: %q %q at %s, %q %q at %s", tok, lit, l.Pos(), rtok, rlit, r.Pos())
+			}
+
+			pos := l.Pos()
+			if pos.Line < last.Line || pos.Line == last.Line && pos.Col < last.Col {
+				t.Fatalf("position went backwards: %s after %s", pos, last)
+			}
+			last = pos
+
+			if tok == token.ILLEGAL && l.Error() == nil {
+				t.Fatalf("ILLEGAL token %q without error", lit)
+			}
+
+			if tok == token.EOF {
+				break
+			}
+		}
+	})
+}
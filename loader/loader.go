@@ -0,0 +1,180 @@
+// Package loader reads the files of a program and the modules they
+// import.
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"oasis/ast"
+	"oasis/diag"
+	"oasis/lexer"
+	"oasis/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ext is the extension added to import paths that have none.
+const Ext = ".oasis"
+
+// Loader finds, parses and caches source files. It implements
+// evaluator.Importer for the files it has loaded.
+type Loader struct {
+	// Path lists the directories searched for imports that do not start
+	// with "./" or "../", after the directory of the importing file.
+	Path []string
+
+	files   map[string]*file
+	order   []string
+	sources map[string]string
+}
+
+type file struct {
+	program *ast.Program
+	// imports maps each import statement to the file name it resolved to.
+	imports map[*ast.ImportStmt]string
+	loaded  bool
+}
+
+func New(path []string) *Loader {
+	return &Loader{Path: path, files: make(map[string]*file), sources: make(map[string]string)}
+}
+
+// Load parses the file name and, recursively, every file it imports. It
+// reports files that cannot be found or parsed and import cycles as
+// diagnostics positioned at the offending import.
+func (l *Loader) Load(name string) (*ast.Program, error) {
+	name = filepath.Clean(name)
+	if err := l.load(name, nil); err != nil {
+		return nil, err
+	}
+	return l.files[name].program, nil
+}
+
+// load loads name, which stack imports in that order.
+func (l *Loader) load(name string, stack []string) error {
+	f, ok := l.files[name]
+	if ok && f.loaded {
+		return nil
+	}
+
+	if !ok {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		l.sources[name] = string(src)
+
+		p := parser.New(lexer.NewFile(name, string(src)))
+		program := p.ParseProgram()
+		if program == nil {
+			return p.Error()
+		}
+
+		f = &file{program: program, imports: make(map[*ast.ImportStmt]string)}
+		l.files[name] = f
+		l.order = append(l.order, name)
+	}
+
+	stack = append(stack, name)
+	for _, stmt := range f.program.Stmts {
+		imp, ok := stmt.(*ast.ImportStmt)
+		if !ok {
+			continue
+		}
+
+		target, err := l.resolve(imp)
+		if err != nil {
+			return err
+		}
+		f.imports[imp] = target
+
+		for i, s := range stack {
+			if s == target {
+				chain := append(append([]string(nil), stack[i:]...), target)
+				return &diag.Diagnostic{
+					Pos:   imp.Path.Pos(),
+					Len:   diag.Span(imp.Path.String()),
+					Msg:   "import cycle: " + strings.Join(chain, " -> "),
+					Label: "imports " + target,
+				}
+			}
+		}
+
+		if err := l.load(target, stack); err != nil {
+			var d *diag.Diagnostic
+			if errors.As(err, &d) {
+				return err
+			}
+			return importError(imp, fmt.Sprintf("cannot load module %s: %s", imp.Path, err))
+		}
+	}
+	f.loaded = true
+
+	return nil
+}
+
+// resolve returns the name of the file imported by stmt.
+func (l *Loader) resolve(stmt *ast.ImportStmt) (string, error) {
+	path := filepath.FromSlash(stmt.Path.Value)
+	if filepath.Ext(path) == "" {
+		path += Ext
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	dir := filepath.Dir(stmt.Pos().File)
+	if strings.HasPrefix(stmt.Path.Value, "./") || strings.HasPrefix(stmt.Path.Value, "../") {
+		return filepath.Join(dir, path), nil
+	}
+
+	dirs := append([]string{dir}, l.Path...)
+	for _, dir := range dirs {
+		name := filepath.Join(dir, path)
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", importError(stmt, err.Error())
+		}
+	}
+
+	d := importError(stmt, fmt.Sprintf("cannot find module %s", stmt.Path))
+	d.Hints = append(d.Hints, "searched "+strings.Join(dirs, ", "))
+	return "", d
+}
+
+// Import returns the file and program of a module that was imported by
+// a file passed to Load.
+func (l *Loader) Import(stmt *ast.ImportStmt) (string, *ast.Program, error) {
+	f, ok := l.files[stmt.Pos().File]
+	if !ok {
+		return "", nil, fmt.Errorf("%s was not loaded", stmt.Pos().File)
+	}
+	name, ok := f.imports[stmt]
+	if !ok {
+		return "", nil, fmt.Errorf("import at %s was not loaded", stmt.Pos())
+	}
+	return name, l.files[name].program, nil
+}
+
+// Programs returns the programs of every file parsed so far, in the
+// order they were read.
+func (l *Loader) Programs() []*ast.Program {
+	programs := make([]*ast.Program, len(l.order))
+	for i, name := range l.order {
+		programs[i] = l.files[name].program
+	}
+	return programs
+}
+
+// Sources returns the contents of every file read so far by name, for
+// printing diagnostics.
+func (l *Loader) Sources() map[string]string {
+	return l.sources
+}
+
+func importError(stmt *ast.ImportStmt, msg string) *diag.Diagnostic {
+	return &diag.Diagnostic{Pos: stmt.Path.Pos(), Len: diag.Span(stmt.Path.String()), Msg: msg}
+}
@@ -0,0 +1,193 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"oasis/diag"
+	"oasis/evaluator"
+	"oasis/object"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles creates files, a map from slash-separated names to their
+// contents, under a temporary directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, src := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func run(t *testing.T, l *Loader, name string) (object.Object, string, error) {
+	t.Helper()
+
+	program, err := l.Load(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var out bytes.Buffer
+	in := evaluator.New()
+	in.Stdout = &out
+	in.Importer = l
+
+	val, err := in.Run(context.Background(), program, object.NewEnv(nil))
+	return val, out.String(), err
+}
+
+func TestImports(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.oasis": `
+import "./geo/square"
+import "util"
+import "lib"
+square.Square(util.Double(3)) + lib.Answer`,
+		"geo/square.oasis": `
+import "../util"
+let Square = func(x) { util.Double(x) * x / 2 }`,
+		"util.oasis": `
+print("util loaded")
+let Double = func(x) { x * 2 }`,
+		"std/lib.oasis": `let Answer = 6`,
+	})
+
+	l := New([]string{filepath.Join(dir, "std")})
+	val, out, err := run(t, l, filepath.Join(dir, "main.oasis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !object.Equal(val, &object.Int{Value: 42}) {
+		t.Fatalf("expected 42, got %s", val)
+	}
+	if out != "util loaded\n" {
+		t.Fatalf("expected util to run once, got output %q", out)
+	}
+	if len(l.Sources()) != 4 {
+		t.Fatalf("expected 4 sources, got %d", len(l.Sources()))
+	}
+}
+
+func TestModuleStructs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.oasis": `
+import "geo"
+let p = geo.Point { x: 3, y: 4 }
+p.x += 1
+geo.Area(p)`,
+		"geo.oasis": `
+struct Point { x: int, y: int }
+let Area = func(p: Point) -> int { p.x * p.y }`,
+	})
+
+	val, _, err := run(t, New(nil), filepath.Join(dir, "main.oasis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !object.Equal(val, &object.Int{Value: 16}) {
+		t.Fatalf("expected 16, got %s", val)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		err   string
+	}{
+		{
+			map[string]string{"main.oasis": `import "missing"`},
+			`main.oasis:1:8: cannot find module "missing"`,
+		},
+		{
+			map[string]string{"main.oasis": `import "./sub/missing"`},
+			`main.oasis:1:8: cannot load module "./sub/missing": open sub/missing.oasis: no such file or directory`,
+		},
+		{
+			map[string]string{"main.oasis": `import "a"`, "a.oasis": "let x = "},
+			`a.oasis:1:9: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got "EOF"`,
+		},
+		{
+			map[string]string{"main.oasis": `import "main"`},
+			`main.oasis:1:8: import cycle: main.oasis -> main.oasis`,
+		},
+		{
+			map[string]string{
+				"main.oasis": `import "a"`,
+				"a.oasis":    "import \"b\"\nlet A = 1",
+				"b.oasis":    "let B = 2\nimport \"./a\"",
+			},
+			`b.oasis:2:8: import cycle: a.oasis -> b.oasis -> a.oasis`,
+		},
+	}
+
+	for i, tt := range tests {
+		dir := writeFiles(t, tt.files)
+
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		_, err = New(nil).Load("main.oasis")
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+
+		var d *diag.Diagnostic
+		if !errors.As(err, &d) {
+			t.Fatalf("tests[%d]: expected a diagnostic, got %v", i, err)
+		}
+		if d.Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.err, d.Error())
+		}
+	}
+}
+
+func TestMembers(t *testing.T) {
+	tests := []struct {
+		main string
+		err  string
+	}{
+		{"m.Exported()", ""},
+		{"m.hidden", `main.oasis:2:3: cannot refer to unexported name m.hidden`},
+		{"m.Nope", `main.oasis:2:3: undefined: m.Nope`},
+		{"let x = 1\nx.Y", `main.oasis:3:2: x (type int) has no member Y`},
+	}
+
+	for i, tt := range tests {
+		dir := writeFiles(t, map[string]string{
+			"main.oasis": "import \"m\"\n" + tt.main,
+			"m.oasis":    "let hidden = 1\nlet Exported = func() { hidden }",
+		})
+
+		_, _, err := run(t, New(nil), filepath.Join(dir, "main.oasis"))
+		if tt.err == "" {
+			if err != nil {
+				t.Fatalf("tests[%d]: %s", i, err)
+			}
+			continue
+		}
+
+		var rerr *evaluator.Error
+		if !errors.As(err, &rerr) {
+			t.Fatalf("tests[%d]: expected runtime error, got %v", i, err)
+		}
+		rerr.Pos.File = filepath.Base(rerr.Pos.File)
+		if rerr.Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.err, rerr.Error())
+		}
+	}
+}
@@ -1,33 +1,237 @@
-package main
+// Package oasis embeds the Oasis scripting language in Go programs.
+//
+// A script is compiled once with Compile, or with Load for programs
+// spread over several files, after which its globals can be read and
+// written with Get and Set, the program executed with Run and the
+// functions it defines invoked with Call. Values cross the boundary
+// as plain Go values: nil, bool, integers (returned as int64, or as
+// *big.Int when they do not fit), string, slices (returned as []any),
+// maps (returned as map[string]any when every key is a string,
+// map[any]any otherwise), structs (returned as map[string]any) and
+// functions.
+package oasis
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"oasis/ast"
+	"oasis/check"
+	"oasis/diag"
+	"oasis/evaluator"
+	"oasis/fold"
 	"oasis/lexer"
+	"oasis/loader"
+	"oasis/object"
 	"oasis/parser"
-	"os"
+	"oasis/types"
 )
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "no input file specified")
-		os.Exit(1)
+type Value = object.Object
+
+type Limits = evaluator.Limits
+
+type Truthiness = evaluator.Truthiness
+
+const (
+	Strict = evaluator.Strict
+	Truthy = evaluator.Truthy
+)
+
+const Variadic = object.Variadic
+
+// Options configure how a script is compiled and run.
+type Options struct {
+	// Immutable makes let bindings immutable: only those declared with
+	// let mut may be assigned to.
+	Immutable bool
+
+	// BigInts enables arbitrary-precision ints. Arithmetic that would
+	// overflow an int64, including constant arithmetic, then produces a
+	// big int instead of wrapping around.
+	BigInts bool
+}
+
+type Script struct {
+	program  *ast.Program
+	warnings []*diag.Diagnostic
+	globals  *object.Env
+	interp   *evaluator.Interpreter
+}
+
+// Compile parses src and evaluates its constant expressions. The
+// returned error is a *diag.Diagnostic when src is not a valid program.
+func Compile(src string) (*Script, error) {
+	return CompileOptions(src, Options{})
+}
+
+// CompileOptions is like Compile, but with the behavior selected by
+// opts.
+func CompileOptions(src string, opts Options) (*Script, error) {
+	p := parser.New(lexer.New(src))
+
+	program := p.ParseProgram()
+	if program == nil {
+		return nil, p.Error()
 	}
 
-	data, err := os.ReadFile(os.Args[1])
+	warnings, err := analyze(program, opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	l := lexer.New(string(data))
-	p := parser.New(l)
-	p.Error()
+	interp := evaluator.New()
+	interp.BigInts = opts.BigInts
 
-	program := p.ParseProgram()
-	if program == nil {
-		fmt.Fprintf(os.Stderr, "%s", p.Error())
-		os.Exit(1)
+	return &Script{
+		program:  program,
+		warnings: warnings,
+		globals:  object.NewEnv(nil),
+		interp:   interp,
+	}, nil
+}
+
+// Load compiles the program in the file name and the files it imports,
+// which l finds and parses. Diagnostics and runtime errors name the file
+// they occurred in; l.Sources has the contents needed to print them.
+func Load(l *loader.Loader, name string) (*Script, error) {
+	return LoadOptions(l, name, Options{})
+}
+
+// LoadOptions is like Load, but compiles every file with the behavior
+// selected by opts.
+func LoadOptions(l *loader.Loader, name string, opts Options) (*Script, error) {
+	program, err := l.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []*diag.Diagnostic
+	for _, p := range l.Programs() {
+		w, err := analyze(p, opts)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w...)
+	}
+
+	interp := evaluator.New()
+	interp.Importer = l
+	interp.BigInts = opts.BigInts
+
+	return &Script{
+		program:  program,
+		warnings: warnings,
+		globals:  object.NewEnv(nil),
+		interp:   interp,
+	}, nil
+}
+
+// analyze folds the constant expressions of program and checks it. It
+// returns the warnings for program, or its first error.
+func analyze(program *ast.Program, opts Options) ([]*diag.Diagnostic, error) {
+	if errs := fold.Program(program, opts.BigInts); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	var warnings []*diag.Diagnostic
+	checks := check.Program(program, check.Options{Immutable: opts.Immutable})
+	for _, d := range append(checks, types.Check(program)...) {
+		if d.Severity == diag.Error {
+			return nil, d
+		}
+		warnings = append(warnings, d)
+	}
+	return warnings, nil
+}
+
+// Warnings returns the problems found in the script that do not stop it
+// from running, such as unreachable match arms.
+func (s *Script) Warnings() []*diag.Diagnostic {
+	return s.warnings
+}
+
+// Run executes the top-level statements of the script. Runtime failures
+// are reported as *evaluator.Error.
+func (s *Script) Run(ctx context.Context) error {
+	_, err := s.interp.Run(ctx, s.program, s.globals)
+	return err
+}
+
+// Register makes fn callable from the script as the builtin name. It
+// takes arity arguments, or any number if arity is Variadic. Errors
+// returned by fn are reported at the call site; wrap them in an
+// *object.ArgError to point at a specific argument instead.
+func (s *Script) Register(name string, arity int, doc string, fn func(args []Value) (Value, error)) error {
+	if err := s.interp.Builtins().Register(name, arity, doc, fn); err != nil {
+		return fmt.Errorf("oasis: %w", err)
 	}
+	return nil
+}
+
+// SetLimits bounds the resources each Run or Call may use. Scripts from
+// untrusted sources should always run with limits and a context that
+// can be canceled.
+func (s *Script) SetLimits(limits Limits) {
+	s.interp.Limits = limits
+}
+
+// SetTruthiness selects whether conditions and the logical operators
+// require booleans (Strict, the default) or accept any value (Truthy).
+func (s *Script) SetTruthiness(t Truthiness) {
+	s.interp.Truthiness = t
+}
+
+// SetOutput sets the destination of the print builtin.
+func (s *Script) SetOutput(w io.Writer) {
+	s.interp.Stdout = w
+}
 
-	fmt.Println(program)
+// Set defines the global name, converting value to an Oasis value.
+func (s *Script) Set(name string, value any) error {
+	obj, err := s.toValue(value)
+	if err != nil {
+		return fmt.Errorf("oasis: set %s: %w", name, err)
+	}
+	s.globals.Define(name, obj)
+	return nil
+}
+
+// Get returns the global name converted to a Go value.
+func (s *Script) Get(name string) (any, error) {
+	obj, ok := s.globals.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("oasis: undefined: %s", name)
+	}
+	return s.fromValue(obj), nil
+}
+
+// Call invokes the global function name with args.
+func (s *Script) Call(name string, args ...any) (any, error) {
+	return s.CallContext(context.Background(), name, args...)
+}
+
+func (s *Script) CallContext(ctx context.Context, name string, args ...any) (any, error) {
+	fn, ok := s.globals.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("oasis: undefined: %s", name)
+	}
+	return s.call(ctx, fn, args)
+}
+
+func (s *Script) call(ctx context.Context, fn object.Object, args []any) (any, error) {
+	objs := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := s.toValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("oasis: argument %d: %w", i, err)
+		}
+		objs[i] = obj
+	}
+
+	result, err := s.interp.Call(ctx, fn, objs)
+	if err != nil {
+		return nil, err
+	}
+	return s.fromValue(result), nil
 }
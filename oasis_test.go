@@ -0,0 +1,465 @@
+package oasis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"oasis/diag"
+	"oasis/evaluator"
+	"oasis/loader"
+	"oasis/object"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScript(t *testing.T) {
+	s, err := Compile(`
+let scale = func(x) { x * factor }
+let total = scale(base)
+let apply = func(f, x) { f(x) }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Set("factor", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("base", int64(14)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	total, err := s.Get("total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != int64(42) {
+		t.Fatalf("expected 42, got %#v", total)
+	}
+
+	result, err := s.Call("scale", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != int64(15) {
+		t.Fatalf("expected 15, got %#v", result)
+	}
+
+	result, err = s.Call("apply", func(n int) int { return n + 1 }, 41)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != int64(42) {
+		t.Fatalf("expected 42, got %#v", result)
+	}
+
+	scale, err := s.Get("scale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = scale.(func(...any) (any, error))(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != int64(6) {
+		t.Fatalf("expected 6, got %#v", result)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		in  any
+		out any
+	}{
+		{nil, nil},
+		{true, true},
+		{7, int64(7)},
+		{uint8(7), int64(7)},
+		{"héllo", "héllo"},
+		{[]int{1, 2}, []any{int64(1), int64(2)}},
+		{[]any{"a", false, nil}, []any{"a", false, nil}},
+		{map[string]int{"a": 1}, map[string]any{"a": int64(1)}},
+		{map[int]bool{1: true}, map[any]any{int64(1): true}},
+		{map[string][]string{"k": {"v"}}, map[string]any{"k": []any{"v"}}},
+		{new(big.Int).Lsh(big.NewInt(1), 64), new(big.Int).Lsh(big.NewInt(1), 64)},
+		{big.NewInt(-5), int64(-5)},
+	}
+
+	s, err := Compile("let id = func(x) { x }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range tests {
+		if err := s.Set("v", tt.in); err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+
+		out, err := s.Get("v")
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("tests[%d]: expected %#v, got %#v", i, tt.out, out)
+		}
+
+		out, err = s.Call("id", tt.in)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("tests[%d]: expected %#v, got %#v", i, tt.out, out)
+		}
+	}
+
+	if err := s.Set("v", uint64(1<<63)); err == nil {
+		t.Fatalf("expected overflow error")
+	}
+	if err := s.Set("small", func(n int64) int64 { return n }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Call("small", new(big.Int).Lsh(big.NewInt(1), 64)); err == nil {
+		t.Fatalf("expected overflow error")
+	}
+	if err := s.Set("v", 1.5); err == nil {
+		t.Fatalf("expected conversion error")
+	}
+}
+
+func TestStructConversion(t *testing.T) {
+	s, err := Compile(`
+struct Point { x: int, y: int }
+struct Node { value, next }
+let p = Point { x: 1, y: 2 }
+let n = Node { value: p, next: null }
+n.next = n
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := s.Get("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"x": int64(1), "y": int64(2)}; !reflect.DeepEqual(p, want) {
+		t.Fatalf("expected %#v, got %#v", want, p)
+	}
+
+	n, err := s.Get("n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, ok := n.(map[string]any)
+	if !ok || !reflect.DeepEqual(fields["value"], p) {
+		t.Fatalf("expected a map holding p, got %#v", n)
+	}
+	if next, ok := fields["next"].(map[string]any); !ok || reflect.ValueOf(next).Pointer() != reflect.ValueOf(fields).Pointer() {
+		t.Fatalf("expected the recurring struct to be the same map")
+	}
+}
+
+func TestGoFuncs(t *testing.T) {
+	s, err := Compile("let r = join(xs, sep)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Set("xs", []string{"a", "b"})
+	s.Set("sep", "-")
+	s.Set("join", func(xs []string, sep string) (string, error) {
+		if len(xs) == 0 {
+			return "", errors.New("nothing to join")
+		}
+		out := xs[0]
+		for _, x := range xs[1:] {
+			out += sep + x
+		}
+		return out, nil
+	})
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := s.Get("r"); r != "a-b" {
+		t.Fatalf("expected %q, got %#v", "a-b", r)
+	}
+
+	s.Set("xs", []string{})
+	err = s.Run(context.Background())
+
+	var rerr *evaluator.Error
+	if !errors.As(err, &rerr) || rerr.Pos.Line != 1 || rerr.Pos.Col != 9 {
+		t.Fatalf("expected positioned runtime error, got %v", err)
+	}
+
+	s.Set("xs", []int{1})
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatalf("expected argument type error")
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile("let = 1")
+
+	var d *diag.Diagnostic
+	if !errors.As(err, &d) {
+		t.Fatalf("expected diagnostic, got %v", err)
+	}
+}
+
+func TestCompileChecks(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"const limit = 10\nlimit += 1", "2:1: cannot assign to constant limit"},
+		{"let seconds = 1 << 63", "1:17: constant 1 << 63 overflows int"},
+		{"let shift = 1 << -1", "1:15: negative shift count -1"},
+		{"let f = func() { 1 / 0 }", "1:20: integer division by zero"},
+		{"let f = func(n: int) -> str { n }", "1:31: cannot use n (type int) as str in return"},
+		{"struct P { x, y }\nP { x: 1, z: 2 }", "2:11: unknown field z in P literal"},
+	}
+
+	for i, tt := range tests {
+		_, err := Compile(tt.src)
+
+		var d *diag.Diagnostic
+		if !errors.As(err, &d) || d.Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %v", i, tt.err, err)
+		}
+	}
+
+	s, err := Compile("const day = 24 * 60 * 60\nlet week = 7 * day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if week, _ := s.Get("week"); week != int64(604800) {
+		t.Fatalf("expected 604800, got %#v", week)
+	}
+}
+
+func TestConstantInts(t *testing.T) {
+	src := `
+let a = 9223372036854775807
+let folded = 9223372036854775807 + 1
+let computed = a + 1`
+
+	_, err := Compile(src)
+	var d *diag.Diagnostic
+	if !errors.As(err, &d) || d.Error() != "3:34: constant 9223372036854775807 + 1 overflows int" {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+
+	s, err := CompileOptions(src, Options{BigInts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	folded, _ := s.Get("folded")
+	computed, _ := s.Get("computed")
+	if want := new(big.Int).Lsh(big.NewInt(1), 63); !reflect.DeepEqual(folded, want) || !reflect.DeepEqual(computed, want) {
+		t.Fatalf("expected %v, got folded %#v, computed %#v", want, folded, computed)
+	}
+}
+
+func TestImmutable(t *testing.T) {
+	src := "let mut count = 0\nlet limit = 3\nwhile count < limit { count += 1 }"
+	if _, err := CompileOptions(src, Options{Immutable: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	src += "\nlimit = 4"
+	if _, err := Compile(src); err != nil {
+		t.Fatal(err)
+	}
+	_, err := CompileOptions(src, Options{Immutable: true})
+	if err == nil || err.Error() != "4:1: cannot assign to immutable limit" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	s, err := Compile("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestUndefined(t *testing.T) {
+	s, err := Compile("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get("nope"); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := s.Call("nope"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s, err := Compile("log(1, 2)\nlet port = config(8080)\nprint(port)\nconfig(true)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s.SetOutput(&out)
+
+	var logged []Value
+	if err := s.Register("log", Variadic, "log(args...) records args.", func(args []Value) (Value, error) {
+		logged = append(logged, args...)
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register("config", 1, "config(default) returns the configured port.", func(args []Value) (Value, error) {
+		if _, ok := args[0].(*object.Int); !ok {
+			return nil, object.ArgTypeError(0, "int", args[0])
+		}
+		return args[0], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Run(context.Background())
+	if err == nil || err.Error() != "4:8: config: argument 1: want int, got bool" {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	if len(logged) != 2 {
+		t.Fatalf("expected 2 logged values, got %d", len(logged))
+	}
+	if port, _ := s.Get("port"); port != int64(8080) {
+		t.Fatalf("expected 8080, got %#v", port)
+	}
+	if out.String() != "8080\n" {
+		t.Fatalf("wrong output: %q", out.String())
+	}
+
+	if err := s.Register("config", 1, "", nil); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s, err := Compile("let spin = func() { while true {} }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetLimits(Limits{MaxSteps: 10000})
+	if _, err := s.Call("spin"); !errors.Is(err, evaluator.ErrStepLimit) {
+		t.Fatalf("expected step limit, got %v", err)
+	}
+
+	s.SetLimits(Limits{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.CallContext(ctx, "spin"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline, got %v", err)
+	}
+}
+
+func TestTruthiness(t *testing.T) {
+	s, err := Compile("let port = func(configured) { configured || 8080 }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Call("port", 0); !errors.Is(err, evaluator.ErrType) {
+		t.Fatalf("expected type error, got %v", err)
+	}
+
+	s.SetTruthiness(Truthy)
+	for configured, want := range map[any]int64{0: 8080, nil: 8080, 9000: 9000} {
+		got, err := s.Call("port", configured)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("port(%v): expected %d, got %#v", configured, want, got)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	s, err := Compile("let f = func(x) { match x { _ => 1, 2 => 2 } }")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := s.Warnings()
+	if len(warnings) != 1 || warnings[0].Error() != "1:37: unreachable match arm" {
+		t.Fatalf("wrong warnings: %v", warnings)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.oasis":  "import \"greet\"\nlet hello = func(name) { greet.Hello(name) }",
+		"greet.oasis": "let Hello = func(name) { \"hello, \" + name }\nmatch 1 { _ => 1, 2 => 2 }",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := Load(loader.New(nil), filepath.Join(dir, "main.oasis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Call("hello", "oasis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hello, oasis" {
+		t.Fatalf("expected %q, got %#v", "hello, oasis", got)
+	}
+
+	warnings := s.Warnings()
+	if len(warnings) != 1 || warnings[0].Pos.File != filepath.Join(dir, "greet.oasis") {
+		t.Fatalf("wrong warnings: %v", warnings)
+	}
+}
@@ -0,0 +1,38 @@
+package object
+
+import "fmt"
+
+type BuiltinFunc func(args []Object) (Object, error)
+
+// Variadic is the arity of builtins that accept any number of arguments.
+const Variadic = -1
+
+type Builtin struct {
+	Name  string
+	Arity int
+	Doc   string
+	Fn    BuiltinFunc
+}
+
+func (b *Builtin) Type() Type     { return BUILTIN }
+func (b *Builtin) String() string { return "builtin " + b.Name }
+
+// ArgError reports a problem with the argument at Index, so that it can
+// be attributed to that argument at the call site.
+type ArgError struct {
+	Index int
+	Err   error
+}
+
+func (e *ArgError) Error() string {
+	return fmt.Sprintf("argument %d: %s", e.Index+1, e.Err)
+}
+
+func (e *ArgError) Unwrap() error {
+	return e.Err
+}
+
+// ArgTypeError returns an ArgError for an argument of the wrong type.
+func ArgTypeError(index int, want string, got Object) error {
+	return &ArgError{Index: index, Err: fmt.Errorf("want %s, got %s", want, got.Type())}
+}
@@ -0,0 +1,58 @@
+package object
+
+type Env struct {
+	store  map[string]Object
+	consts map[string]bool
+	outer  *Env
+}
+
+func NewEnv(outer *Env) *Env {
+	return &Env{store: make(map[string]Object), outer: outer}
+}
+
+func (e *Env) Get(name string) (Object, bool) {
+	for ; e != nil; e = e.outer {
+		if obj, ok := e.store[name]; ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// Define binds name in e, shadowing any binding in an outer scope.
+func (e *Env) Define(name string, value Object) {
+	e.store[name] = value
+	delete(e.consts, name)
+}
+
+// DefineConst is Define for a binding that cannot be assigned to.
+func (e *Env) DefineConst(name string, value Object) {
+	e.Define(name, value)
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+}
+
+// IsConst reports whether the innermost binding of name was made by
+// DefineConst.
+func (e *Env) IsConst(name string) bool {
+	for ; e != nil; e = e.outer {
+		if _, ok := e.store[name]; ok {
+			return e.consts[name]
+		}
+	}
+	return false
+}
+
+// Assign rebinds the innermost existing binding of name. It reports
+// false if name is not bound.
+func (e *Env) Assign(name string, value Object) bool {
+	for ; e != nil; e = e.outer {
+		if _, ok := e.store[name]; ok {
+			e.store[name] = value
+			return true
+		}
+	}
+	return false
+}
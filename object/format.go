@@ -0,0 +1,73 @@
+package object
+
+import "bytes"
+
+// format writes obj to out the way its String method does. seen holds the
+// structs being written, so that a struct that contains itself is written
+// as Name {...} where it recurs.
+func format(out *bytes.Buffer, obj Object, seen map[*Struct]bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		out.WriteString("[")
+		formatList(out, obj.Elems, seen)
+		out.WriteString("]")
+	case *Tuple:
+		out.WriteString("(")
+		formatList(out, obj.Elems, seen)
+		if len(obj.Elems) == 1 {
+			out.WriteString(",")
+		}
+		out.WriteString(")")
+	case *Map:
+		out.WriteString("{")
+		for i, pair := range obj.Pairs {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			format(out, pair.Key, seen)
+			out.WriteString(": ")
+			format(out, pair.Value, seen)
+		}
+		out.WriteString("}")
+	case *Struct:
+		out.WriteString(obj.Def.Name)
+		if seen[obj] {
+			out.WriteString(" {...}")
+			return
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		out.WriteString(" {")
+		for i, val := range obj.Fields {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString(" ")
+			out.WriteString(obj.Def.Fields[i])
+			out.WriteString(": ")
+			format(out, val, seen)
+		}
+		if len(obj.Fields) > 0 {
+			out.WriteString(" ")
+		}
+		out.WriteString("}")
+	default:
+		out.WriteString(obj.String())
+	}
+}
+
+func formatList(out *bytes.Buffer, elems []Object, seen map[*Struct]bool) {
+	for i, elem := range elems {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		format(out, elem, seen)
+	}
+}
+
+func formatString(obj Object) string {
+	var out bytes.Buffer
+	format(&out, obj, make(map[*Struct]bool))
+	return out.String()
+}
@@ -0,0 +1,63 @@
+package object
+
+type HashKey struct {
+	Type Type
+	Int  int64
+	Str  string
+}
+
+// Hashable is implemented by objects that can be used as map keys.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (b *Bool) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: BOOL, Int: 1}
+	}
+	return HashKey{Type: BOOL}
+}
+
+func (i *Int) HashKey() HashKey    { return HashKey{Type: INT, Int: i.Value} }
+func (i *BigInt) HashKey() HashKey { return HashKey{Type: INT, Str: i.Value.String()} }
+func (s *String) HashKey() HashKey { return HashKey{Type: STRING, Str: s.Value} }
+
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// Map is a hash map that remembers the order in which keys were added.
+type Map struct {
+	pairs map[HashKey]int
+	Pairs []MapPair
+}
+
+func NewMap() *Map {
+	return &Map{pairs: make(map[HashKey]int)}
+}
+
+func (m *Map) Type() Type     { return MAP }
+func (m *Map) String() string { return formatString(m) }
+
+func (m *Map) Get(key Hashable) (Object, bool) {
+	if i, ok := m.pairs[key.HashKey()]; ok {
+		return m.Pairs[i].Value, true
+	}
+	return nil, false
+}
+
+func (m *Map) Set(key Hashable, value Object) {
+	hk := key.HashKey()
+	if i, ok := m.pairs[hk]; ok {
+		m.Pairs[i].Value = value
+		return
+	}
+	m.pairs[hk] = len(m.Pairs)
+	m.Pairs = append(m.Pairs, MapPair{Key: key, Value: value})
+}
+
+func (m *Map) Len() int {
+	return len(m.Pairs)
+}
@@ -0,0 +1,247 @@
+package object
+
+import (
+	"math/big"
+	"oasis/ast"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Type int
+
+const (
+	_ Type = iota
+
+	NULL
+	BOOL
+	INT
+	STRING
+	ARRAY
+	TUPLE
+	MAP
+	RANGE
+	FUNC
+	BUILTIN
+	MODULE
+	STRUCT
+	TYPE
+)
+
+var TypeName = map[Type]string{
+	NULL:    "null",
+	BOOL:    "bool",
+	INT:     "int",
+	STRING:  "str",
+	ARRAY:   "array",
+	TUPLE:   "tuple",
+	MAP:     "map",
+	RANGE:   "range",
+	FUNC:    "func",
+	BUILTIN: "builtin",
+	MODULE:  "module",
+	STRUCT:  "struct",
+	TYPE:    "type",
+}
+
+func (t Type) String() string {
+	return TypeName[t]
+}
+
+type Object interface {
+	Type() Type
+	String() string
+}
+
+var (
+	Null  = &NullValue{}
+	True  = &Bool{Value: true}
+	False = &Bool{Value: false}
+)
+
+func NewBool(b bool) *Bool {
+	if b {
+		return True
+	}
+	return False
+}
+
+type NullValue struct{}
+
+func (n *NullValue) Type() Type     { return NULL }
+func (n *NullValue) String() string { return "null" }
+
+type Bool struct {
+	Value bool
+}
+
+func (b *Bool) Type() Type     { return BOOL }
+func (b *Bool) String() string { return strconv.FormatBool(b.Value) }
+
+type Int struct {
+	Value int64
+}
+
+func (i *Int) Type() Type     { return INT }
+func (i *Int) String() string { return strconv.FormatInt(i.Value, 10) }
+
+// BigInt is an int outside the range of int64. Use NewBigInt to create
+// one, so that every value that fits in an int64 stays an *Int.
+type BigInt struct {
+	Value *big.Int
+}
+
+// NewBigInt returns v as an *Int if it fits in an int64 and as a *BigInt
+// otherwise. v must not be modified afterwards.
+func NewBigInt(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Int{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+func (i *BigInt) Type() Type     { return INT }
+func (i *BigInt) String() string { return i.Value.String() }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() Type     { return STRING }
+func (s *String) String() string { return s.Value }
+
+type Array struct {
+	Elems []Object
+}
+
+func (a *Array) Type() Type     { return ARRAY }
+func (a *Array) String() string { return formatString(a) }
+
+// Tuple is a fixed-size sequence of values, compared by value.
+type Tuple struct {
+	Elems []Object
+}
+
+func (t *Tuple) Type() Type     { return TUPLE }
+func (t *Tuple) String() string { return formatString(t) }
+
+// Range is the sequence of ints from Start up to End, including End if
+// Inclusive is set.
+type Range struct {
+	Start     int64
+	End       int64
+	Inclusive bool
+}
+
+func (r *Range) Type() Type { return RANGE }
+func (r *Range) String() string {
+	op := ".."
+	if r.Inclusive {
+		op = "..="
+	}
+	return strconv.FormatInt(r.Start, 10) + op + strconv.FormatInt(r.End, 10)
+}
+
+type Func struct {
+	// Name is the name the function was first bound to with let, if any.
+	Name string
+	Lit  *ast.FuncLit
+	// Env is the scope the literal was evaluated in. It is captured by
+	// reference: the function sees later assignments to the variables in
+	// it, and its own assignments are visible outside.
+	Env *Env
+}
+
+func (f *Func) Type() Type     { return FUNC }
+func (f *Func) String() string { return f.Lit.String() }
+
+// StructType is a type declared with struct. Fields holds the names of
+// its fields in the order they are declared.
+type StructType struct {
+	Name   string
+	Fields []string
+}
+
+func (st *StructType) Type() Type     { return TYPE }
+func (st *StructType) String() string { return "struct " + st.Name }
+
+// Field returns the index of the field name, or -1 if st has no such
+// field.
+func (st *StructType) Field(name string) int {
+	for i, field := range st.Fields {
+		if field == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Struct is a value of a struct type. Fields holds the values of the
+// fields of Def in the order they are declared.
+type Struct struct {
+	Def    *StructType
+	Fields []Object
+}
+
+func (s *Struct) Type() Type     { return STRUCT }
+func (s *Struct) String() string { return formatString(s) }
+
+// Module is an imported file. Env holds its top-level bindings, of which
+// those starting with an upper case letter are exported.
+type Module struct {
+	Name string
+	Path string
+	Env  *Env
+}
+
+func (m *Module) Type() Type     { return MODULE }
+func (m *Module) String() string { return "module " + m.Name }
+
+// Lookup returns the top-level binding name of m, whether or not it is
+// exported.
+func (m *Module) Lookup(name string) (Object, bool) {
+	value, ok := m.Env.store[name]
+	return value, ok
+}
+
+// IsExported reports whether name starts with an upper case letter.
+func IsExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// Equal reports whether a and b are the same value. Null, booleans,
+// integers, strings, ranges and tuples compare by value, everything else
+// by identity.
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Int:
+		b, ok := b.(*Int)
+		return ok && a.Value == b.Value
+	case *BigInt:
+		b, ok := b.(*BigInt)
+		return ok && a.Value.Cmp(b.Value) == 0
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Bool:
+		b, ok := b.(*Bool)
+		return ok && a.Value == b.Value
+	case *Range:
+		b, ok := b.(*Range)
+		return ok && *a == *b
+	case *Tuple:
+		b, ok := b.(*Tuple)
+		if !ok || len(a.Elems) != len(b.Elems) {
+			return false
+		}
+		for i := range a.Elems {
+			if !Equal(a.Elems[i], b.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
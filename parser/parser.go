@@ -1,16 +1,22 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"oasis/ast"
+	"oasis/diag"
 	"oasis/lexer"
 	"oasis/token"
+	"path"
+	"sort"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
 	ASSIGN
+	RANGE
 	LOR
 	LAND
 	XOR
@@ -31,11 +37,14 @@ var precedences = map[token.Token]int{
 	token.SUB_ASSIGN:    ASSIGN,
 	token.MUL_ASSIGN:    ASSIGN,
 	token.DIV_ASSIGN:    ASSIGN,
+	token.MOD_ASSIGN:    ASSIGN,
 	token.AND_ASSIGN:    ASSIGN,
 	token.OR_ASSIGN:     ASSIGN,
 	token.XOR_ASSIGN:    ASSIGN,
 	token.LSHIFT_ASSIGN: ASSIGN,
 	token.RSHIFT_ASSIGN: ASSIGN,
+	token.DOTDOT:        RANGE,
+	token.DOTDOT_EQ:     RANGE,
 	token.LAND:          LAND,
 	token.LOR:           LOR,
 	token.AND:           AND,
@@ -55,6 +64,7 @@ var precedences = map[token.Token]int{
 	token.DIV:           FACTOR,
 	token.MOD:           FACTOR,
 	token.LPAREN:        CALL,
+	token.DOT:           CALL,
 }
 
 type (
@@ -68,6 +78,11 @@ type Parser struct {
 
 	tok token.Token
 	lit string
+	pos token.Pos
+
+	// noStructLit is set in the clauses of if, while, for and match,
+	// where a name followed by "{" is the name and the body.
+	noStructLit bool
 
 	prefixParseFns map[token.Token]prefixParseFn
 	infixParseFns  map[token.Token]infixParseFn
@@ -81,13 +96,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.Token]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdent)
 	p.registerPrefix(token.INT, p.parseIntLit)
+	p.registerPrefix(token.STRING, p.parseStringLit)
+	p.registerPrefix(token.TRUE, p.parseBoolLit)
+	p.registerPrefix(token.FALSE, p.parseBoolLit)
+	p.registerPrefix(token.NULL, p.parseNullLit)
 	p.registerPrefix(token.SUB, p.parsePrefixExpr)
 	p.registerPrefix(token.TILDE, p.parsePrefixExpr)
 	p.registerPrefix(token.NOT, p.parsePrefixExpr)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpr)
 	p.registerPrefix(token.LBRACE, p.parseBlockExpr)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLit)
 	p.registerPrefix(token.IF, p.parseIfExpr)
 	p.registerPrefix(token.WHILE, p.parseWhileExpr)
+	p.registerPrefix(token.FOR, p.parseForExpr)
+	p.registerPrefix(token.MATCH, p.parseMatchExpr)
 	p.registerPrefix(token.FUNC, p.parseFuncLit)
 
 	p.infixParseFns = make(map[token.Token]infixParseFn)
@@ -96,11 +118,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.SUB_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.MUL_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.DIV_ASSIGN, p.parseInfixExpr)
+	p.registerInfix(token.MOD_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.AND_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.OR_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.XOR_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.LSHIFT_ASSIGN, p.parseInfixExpr)
 	p.registerInfix(token.RSHIFT_ASSIGN, p.parseInfixExpr)
+	p.registerInfix(token.DOTDOT, p.parseRangeExpr)
+	p.registerInfix(token.DOTDOT_EQ, p.parseRangeExpr)
 	p.registerInfix(token.LAND, p.parseInfixExpr)
 	p.registerInfix(token.LOR, p.parseInfixExpr)
 	p.registerInfix(token.AND, p.parseInfixExpr)
@@ -120,6 +145,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.DIV, p.parseInfixExpr)
 	p.registerInfix(token.MOD, p.parseInfixExpr)
 	p.registerInfix(token.LPAREN, p.parseCallExpr)
+	p.registerInfix(token.DOT, p.parseSelectorExpr)
 
 	return p
 }
@@ -132,7 +158,12 @@ func (p *Parser) ParseProgram() *ast.Program {
 	stmts := []ast.Stmt{}
 
 	for p.tok != token.EOF {
-		stmt := p.parseStmt()
+		var stmt ast.Stmt
+		if p.tok == token.IMPORT {
+			stmt = p.parseImportStmt()
+		} else {
+			stmt = p.parseStmt()
+		}
 		if stmt == nil {
 			return nil
 		}
@@ -144,8 +175,15 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStmt() ast.Stmt {
 	switch p.tok {
+	case token.IMPORT:
+		p.errorf("imports must be at the top level")
+		return nil
 	case token.LET:
 		return p.parseLetStmt()
+	case token.CONST:
+		return p.parseConstStmt()
+	case token.STRUCT:
+		return p.parseStructStmt()
 	case token.CONTINUE:
 		return p.parseContinueStmt()
 	case token.BREAK:
@@ -171,16 +209,160 @@ func (p *Parser) parseExprStmt() ast.Stmt {
 	return &ast.ExprStmt{Expr: expr}
 }
 
+func (p *Parser) parseImportStmt() ast.Stmt {
+	pos := p.pos
+	p.advance()
+
+	if p.tok != token.STRING {
+		p.unexpected("expected module path after %q", token.IMPORT)
+		return nil
+	}
+	lit := p.parseStringLit().(*ast.StringLit)
+
+	base := path.Base(lit.Value)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	if tok, ident := lexer.New(name).NextToken(); tok != token.IDENT || ident != name {
+		p.err = &diag.Diagnostic{
+			Pos:   lit.ValuePos,
+			Len:   diag.Span(lit.String()),
+			Msg:   fmt.Sprintf("invalid module name %q", name),
+			Hints: []string{"the last element of an import path must be a valid name"},
+		}
+		return nil
+	}
+
+	if !p.expect(token.SEMI) {
+		return nil
+	}
+	p.advance()
+
+	return &ast.ImportStmt{Import: pos, Path: lit, Name: &ast.Ident{NamePos: lit.ValuePos, Value: name}}
+}
+
 func (p *Parser) parseLetStmt() ast.Stmt {
+	pos := p.pos
+	p.advance()
+
+	keyword, mut := "let", false
+	if p.tok == token.MUT {
+		keyword, mut = "let mut", true
+		p.advance()
+	}
+
+	if p.tok != token.IDENT && p.tok != token.LPAREN && p.tok != token.LBRACKET {
+		p.unexpected("expected name or pattern after %q", keyword)
+		return nil
+	}
+	pattern := p.parseBindingPattern()
+	if pattern == nil {
+		return nil
+	}
+
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+
+	value := p.parseInitializer(keyword, pattern, "variables")
+	if value == nil {
+		return nil
+	}
+	return &ast.LetStmt{Let: pos, Mut: mut, Pattern: pattern, Type: typ, Value: value}
+}
+
+func (p *Parser) parseConstStmt() ast.Stmt {
+	pos := p.pos
+	p.advance()
+
+	if p.tok != token.IDENT {
+		p.unexpected("expected name after %q", token.CONST)
+		return nil
+	}
+	name := &ast.Ident{NamePos: p.pos, Value: p.lit}
+	p.advance()
+
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+
+	value := p.parseInitializer("const", name, "constants")
+	if value == nil {
+		return nil
+	}
+	return &ast.ConstStmt{Const: pos, Name: name, Type: typ, Value: value}
+}
+
+// parseStructStmt parses struct Name { field: Type, ... }, whose fields
+// are separated by commas or newlines and may be annotated.
+func (p *Parser) parseStructStmt() ast.Stmt {
+	pos := p.pos
+	p.advance()
+
+	if p.tok != token.IDENT {
+		p.unexpected("expected name after %q", token.STRUCT)
+		return nil
+	}
+	stmt := &ast.StructStmt{Struct: pos, Name: &ast.Ident{NamePos: p.pos, Value: p.lit}}
+	p.advance()
+
+	if p.tok != token.LBRACE {
+		p.unexpected("expected %q after %q", token.LBRACE, "struct "+stmt.Name.Value)
+		return nil
+	}
+	p.advance()
+
+	for p.tok != token.RBRACE {
+		if p.tok != token.IDENT {
+			p.unexpected("expected field name in struct %s", stmt.Name)
+			return nil
+		}
+		field := &ast.Field{Name: &ast.Ident{NamePos: p.pos, Value: p.lit}}
+		p.advance()
+
+		var ok bool
+		if field.Type, ok = p.parseAnnotation(); !ok {
+			return nil
+		}
+		for _, prev := range stmt.Fields {
+			if prev.Name.Value == field.Name.Value {
+				p.err = &diag.Diagnostic{
+					Pos:   field.Name.NamePos,
+					Len:   diag.Span(field.Name.Value),
+					Msg:   fmt.Sprintf("duplicate field %s in struct %s", field.Name, stmt.Name),
+					Notes: []diag.Note{{Pos: prev.Name.NamePos, Len: diag.Span(prev.Name.Value), Msg: "first declared here"}},
+				}
+				return nil
+			}
+		}
+		stmt.Fields = append(stmt.Fields, field)
+
+		if p.tok == token.COMMA || p.tok == token.SEMI {
+			p.advance()
+		} else if p.tok != token.RBRACE {
+			p.unexpected("expected %q or %q after field", token.COMMA, token.RBRACE)
+			return nil
+		}
+	}
 	p.advance()
 
-	if !p.expect(token.IDENT) {
+	if !p.expect(token.SEMI) {
 		return nil
 	}
-	name := &ast.Ident{Value: p.lit}
 	p.advance()
 
-	if !p.expect(token.ASSIGN) {
+	return stmt
+}
+
+// parseInitializer parses the = value; following keyword and the name or
+// pattern being bound. what names the kind of binding in the hint for a
+// missing value.
+func (p *Parser) parseInitializer(keyword string, name ast.Node, what string) ast.Expr {
+	if p.tok != token.ASSIGN {
+		d := p.unexpected("expected %q after %q", token.ASSIGN, keyword+" "+name.String())
+		if d != nil && p.tok == token.SEMI {
+			d.Hints = append(d.Hints, fmt.Sprintf("%s must be initialized: %s %s = ...", what, keyword, name))
+		}
 		return nil
 	}
 	p.advance()
@@ -195,10 +377,11 @@ func (p *Parser) parseLetStmt() ast.Stmt {
 	}
 	p.advance()
 
-	return &ast.LetStmt{Name: name, Value: value}
+	return value
 }
 
 func (p *Parser) parseContinueStmt() ast.Stmt {
+	pos := p.pos
 	p.advance()
 
 	if !p.expect(token.SEMI) {
@@ -206,15 +389,16 @@ func (p *Parser) parseContinueStmt() ast.Stmt {
 	}
 	p.advance()
 
-	return &ast.ContinueStmt{}
+	return &ast.ContinueStmt{Continue: pos}
 }
 
 func (p *Parser) parseBreakStmt() ast.Stmt {
+	pos := p.pos
 	p.advance()
 
 	if p.tok == token.SEMI {
 		p.advance()
-		return &ast.BreakStmt{}
+		return &ast.BreakStmt{Break: pos}
 	}
 
 	value := p.parseExpr(LOWEST)
@@ -227,15 +411,16 @@ func (p *Parser) parseBreakStmt() ast.Stmt {
 	}
 	p.advance()
 
-	return &ast.BreakStmt{Value: value}
+	return &ast.BreakStmt{Break: pos, Value: value}
 }
 
 func (p *Parser) parseReturnStmt() ast.Stmt {
+	pos := p.pos
 	p.advance()
 
 	if p.tok == token.SEMI {
 		p.advance()
-		return &ast.ReturnStmt{}
+		return &ast.ReturnStmt{Return: pos}
 	}
 
 	value := p.parseExpr(LOWEST)
@@ -248,14 +433,13 @@ func (p *Parser) parseReturnStmt() ast.Stmt {
 	}
 	p.advance()
 
-	return &ast.ReturnStmt{Value: value}
+	return &ast.ReturnStmt{Return: pos, Value: value}
 }
 
 func (p *Parser) parseExpr(prec int) ast.Expr {
 	prefix := p.prefixParseFns[p.tok]
 	if prefix == nil {
-		p.err = fmt.Errorf("expected %q, %q, %q, %q, %q or %q, got %q",
-			token.IDENT, token.INT, token.SUB, token.TILDE, token.NOT, token.LPAREN, p.tok)
+		p.unexpected("expected %s", p.expectedExpr())
 		return nil
 	}
 
@@ -267,6 +451,7 @@ func (p *Parser) parseExpr(prec int) ast.Expr {
 	for p.tok != token.RPAREN && p.tok != token.SEMI && p.tok != token.EOF && prec < p.curPrecedence() {
 		infix := p.infixParseFns[p.tok]
 		if infix == nil {
+			p.unexpected("unexpected operator %q", p.tok)
 			return nil
 		}
 
@@ -280,19 +465,41 @@ func (p *Parser) parseExpr(prec int) ast.Expr {
 }
 
 func (p *Parser) parseIdent() ast.Expr {
-	node := &ast.Ident{Value: p.lit}
+	node := &ast.Ident{NamePos: p.pos, Value: p.lit}
 	p.advance()
+
+	if p.tok == token.LBRACE && !p.noStructLit {
+		return p.parseStructLit(node)
+	}
 	return node
 }
 
 func (p *Parser) parseIntLit() ast.Expr {
-	node := &ast.IntLit{Value: p.lit}
+	node := &ast.IntLit{ValuePos: p.pos, Value: p.lit}
+	p.advance()
+	return node
+}
+
+func (p *Parser) parseStringLit() ast.Expr {
+	node := &ast.StringLit{ValuePos: p.pos, Value: token.Unquote(p.lit)}
+	p.advance()
+	return node
+}
+
+func (p *Parser) parseBoolLit() ast.Expr {
+	node := &ast.BoolLit{ValuePos: p.pos, Value: p.tok == token.TRUE}
+	p.advance()
+	return node
+}
+
+func (p *Parser) parseNullLit() ast.Expr {
+	node := &ast.NullLit{ValuePos: p.pos}
 	p.advance()
 	return node
 }
 
 func (p *Parser) parsePrefixExpr() ast.Expr {
-	op := p.tok
+	op, pos := p.tok, p.pos
 	p.advance()
 
 	right := p.parseExpr(PREFIX)
@@ -300,11 +507,11 @@ func (p *Parser) parsePrefixExpr() ast.Expr {
 		return nil
 	}
 
-	return &ast.PrefixExpr{Op: op, Right: right}
+	return &ast.PrefixExpr{OpPos: pos, Op: op, Right: right}
 }
 
 func (p *Parser) parseInfixExpr(left ast.Expr) ast.Expr {
-	op := p.tok
+	op, pos := p.tok, p.pos
 	prec := p.curPrecedence()
 	p.advance()
 
@@ -313,17 +520,44 @@ func (p *Parser) parseInfixExpr(left ast.Expr) ast.Expr {
 		return nil
 	}
 
-	return &ast.InfixExpr{Left: left, Op: op, Right: right}
+	return &ast.InfixExpr{Left: left, OpPos: pos, Op: op, Right: right}
+}
+
+// parseRangeExpr parses a..b and a..=b. Ranges do not associate, since
+// a range is never a valid bound.
+func (p *Parser) parseRangeExpr(left ast.Expr) ast.Expr {
+	if l, ok := left.(*ast.InfixExpr); ok && (l.Op == token.DOTDOT || l.Op == token.DOTDOT_EQ) {
+		p.errorf("range bound cannot be a range")
+		return nil
+	}
+	return p.parseInfixExpr(left)
 }
 
+// parseGroupedExpr parses (x), and the tuple literals (x,) and (x, y).
 func (p *Parser) parseGroupedExpr() ast.Expr {
+	pos := p.pos
 	p.advance()
+	defer p.allowStructLits()()
 
 	expr := p.parseExpr(LOWEST)
 	if expr == nil {
 		return expr
 	}
 
+	if p.tok == token.COMMA {
+		elems := p.parseExprList([]ast.Expr{expr}, token.RPAREN)
+		if elems == nil {
+			return nil
+		}
+		if p.tok != token.RPAREN {
+			p.unexpected("expected %q or %q in tuple", token.COMMA, token.RPAREN)
+			return nil
+		}
+		p.advance()
+
+		return &ast.TupleLit{Lparen: pos, Elems: elems}
+	}
+
 	if !p.expect(token.RPAREN) {
 		return nil
 	}
@@ -332,53 +566,228 @@ func (p *Parser) parseGroupedExpr() ast.Expr {
 	return expr
 }
 
-func (p *Parser) parseCallExpr(left ast.Expr) ast.Expr {
+func (p *Parser) parseArrayLit() ast.Expr {
+	pos := p.pos
 	p.advance()
+	defer p.allowStructLits()()
 
-	args := p.parseCallArgs()
-	if args == nil {
+	elems := p.parseExprList([]ast.Expr{}, token.RBRACKET)
+	if elems == nil {
 		return nil
 	}
 
-	if !p.expect(token.RPAREN) {
+	if p.tok != token.RBRACKET {
+		p.unexpected("expected %q or %q in array", token.COMMA, token.RBRACKET)
 		return nil
 	}
 	p.advance()
 
-	return &ast.CallExpr{Func: left, Args: args}
+	return &ast.ArrayLit{Lbrack: pos, Elems: elems}
 }
 
-func (p *Parser) parseCallArgs() []ast.Expr {
-	args := []ast.Expr{}
+func (p *Parser) parseCallExpr(left ast.Expr) ast.Expr {
+	call := &ast.CallExpr{Func: left, Lparen: p.pos}
+	p.advance()
+	defer p.allowStructLits()()
 
-	if p.tok == token.RPAREN {
-		return args
+	if !p.parseCallArgs(call) {
+		return nil
 	}
 
-	arg := p.parseExpr(LOWEST)
-	if arg == nil {
+	if p.tok != token.RPAREN {
+		p.unexpected("expected %q or %q in argument list", token.COMMA, token.RPAREN)
 		return nil
 	}
-	args = append(args, arg)
+	p.advance()
+
+	return call
+}
+
+func (p *Parser) parseSelectorExpr(left ast.Expr) ast.Expr {
+	pos := p.pos
+	p.advance()
+
+	if p.tok != token.IDENT {
+		p.unexpected("expected name after %q", token.DOT)
+		return nil
+	}
+	sel := &ast.Ident{NamePos: p.pos, Value: p.lit}
+	p.advance()
+
+	node := &ast.SelectorExpr{X: left, Dot: pos, Sel: sel}
+	if p.tok == token.LBRACE && !p.noStructLit {
+		return p.parseStructLit(node)
+	}
+	return node
+}
+
+// parseStructLit parses the fields of the struct literal typ { ... },
+// which are separated by commas or newlines.
+func (p *Parser) parseStructLit(typ ast.Expr) ast.Expr {
+	lit := &ast.StructLit{Type: typ, Lbrace: p.pos}
+	p.advance()
+	defer p.allowStructLits()()
+
+	for p.tok != token.RBRACE {
+		if p.tok != token.IDENT {
+			p.unexpected("expected field name in struct literal")
+			return nil
+		}
+		field := &ast.FieldValue{Name: &ast.Ident{NamePos: p.pos, Value: p.lit}}
+		p.advance()
+
+		if p.tok != token.COLON {
+			p.unexpected("expected %q after field name %s", token.COLON, field.Name)
+			return nil
+		}
+		field.Colon = p.pos
+		p.advance()
+
+		if field.Value = p.parseExpr(LOWEST); field.Value == nil {
+			return nil
+		}
+		for _, prev := range lit.Fields {
+			if prev.Name.Value == field.Name.Value {
+				p.err = &diag.Diagnostic{
+					Pos:   field.Name.NamePos,
+					Len:   diag.Span(field.Name.Value),
+					Msg:   fmt.Sprintf("duplicate field %s in struct literal", field.Name),
+					Notes: []diag.Note{{Pos: prev.Name.NamePos, Len: diag.Span(prev.Name.Value), Msg: "first given here"}},
+				}
+				return nil
+			}
+		}
+		lit.Fields = append(lit.Fields, field)
+
+		if p.tok == token.COMMA || p.tok == token.SEMI {
+			p.advance()
+		} else if p.tok != token.RBRACE {
+			p.unexpected("expected %q or %q after field", token.COMMA, token.RBRACE)
+			return nil
+		}
+	}
+	p.advance()
+
+	return lit
+}
+
+// parseClause parses the expression between if, while, for or match and
+// the body.
+func (p *Parser) parseClause() ast.Expr {
+	outer := p.noStructLit
+	p.noStructLit = true
+	defer func() { p.noStructLit = outer }()
+
+	return p.parseExpr(LOWEST)
+}
+
+// allowStructLits allows struct literals inside the brackets of a clause
+// and returns a function that restores the setting outside them.
+func (p *Parser) allowStructLits() func() {
+	outer := p.noStructLit
+	p.noStructLit = false
+	return func() { p.noStructLit = outer }
+}
+
+// parseExprList parses a comma-separated list of expressions, which may
+// have a trailing comma, up to the closing token end and appends them to
+// list. If list already holds an expression, the list starts at the
+// comma following it.
+func (p *Parser) parseExprList(list []ast.Expr, end token.Token) []ast.Expr {
+	if len(list) == 0 {
+		if p.tok == end {
+			return list
+		}
+
+		expr := p.parseExpr(LOWEST)
+		if expr == nil {
+			return nil
+		}
+		list = append(list, expr)
+	}
+
 	for p.tok == token.COMMA {
 		p.advance()
 
-		if p.tok == token.RPAREN {
+		if p.tok == end {
 			break
 		}
 
-		arg = p.parseExpr(LOWEST)
-		if arg == nil {
+		expr := p.parseExpr(LOWEST)
+		if expr == nil {
 			return nil
 		}
-		args = append(args, arg)
+		list = append(list, expr)
 	}
 
-	return args
+	return list
+}
+
+// parseCallArgs parses the arguments of call: expressions, ...spreads
+// of them, and name: value arguments, which must come last and name
+// different parameters.
+func (p *Parser) parseCallArgs(call *ast.CallExpr) bool {
+	call.Args = []ast.Expr{}
+
+	for p.tok != token.RPAREN {
+		var arg ast.Expr
+		if p.tok == token.ELLIPSIS {
+			pos := p.pos
+			p.advance()
+
+			if x := p.parseExpr(LOWEST); x != nil {
+				arg = &ast.SpreadExpr{Ellipsis: pos, X: x}
+			}
+		} else {
+			arg = p.parseExpr(LOWEST)
+		}
+		if arg == nil {
+			return false
+		}
+
+		if name, ok := arg.(*ast.Ident); ok && p.tok == token.COLON {
+			named := &ast.NamedArg{Name: name, Colon: p.pos}
+			p.advance()
+
+			if named.Value = p.parseExpr(LOWEST); named.Value == nil {
+				return false
+			}
+			for _, prev := range call.Named {
+				if prev.Name.Value == name.Value {
+					p.err = &diag.Diagnostic{
+						Pos:   name.NamePos,
+						Len:   diag.Span(name.Value),
+						Msg:   fmt.Sprintf("duplicate argument %s", name.Value),
+						Notes: []diag.Note{{Pos: prev.Name.NamePos, Len: diag.Span(name.Value), Msg: "first given here"}},
+					}
+					return false
+				}
+			}
+			call.Named = append(call.Named, named)
+		} else if len(call.Named) > 0 {
+			p.err = &diag.Diagnostic{
+				Pos: arg.Pos(),
+				Len: diag.Span(arg.String()),
+				Msg: "positional argument after named argument",
+			}
+			return false
+		} else {
+			call.Args = append(call.Args, arg)
+		}
+
+		if p.tok != token.COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return true
 }
 
 func (p *Parser) parseBlockExpr() ast.Expr {
+	pos := p.pos
 	p.advance()
+	defer p.allowStructLits()()
 
 	stmts := []ast.Stmt{}
 	for p.tok != token.RBRACE && p.tok != token.EOF {
@@ -394,18 +803,23 @@ func (p *Parser) parseBlockExpr() ast.Expr {
 	}
 	p.advance()
 
-	return &ast.BlockExpr{Stmts: stmts}
+	return &ast.BlockExpr{Lbrace: pos, Stmts: stmts}
 }
 
 func (p *Parser) parseIfExpr() ast.Expr {
+	pos := p.pos
 	p.advance()
 
-	condition := p.parseExpr(LOWEST)
+	condition := p.parseClause()
 	if condition == nil {
 		return nil
 	}
 
-	trueCase := p.parseExpr(LOWEST)
+	if !p.expectBody("if condition") {
+		return nil
+	}
+
+	trueCase := p.parseBlockExpr()
 	if trueCase == nil {
 		return nil
 	}
@@ -413,91 +827,566 @@ func (p *Parser) parseIfExpr() ast.Expr {
 	if p.tok == token.ELSE {
 		p.advance()
 
-		falseCase := p.parseExpr(LOWEST)
+		var falseCase ast.Expr
+		if p.tok == token.IF {
+			falseCase = p.parseIfExpr()
+		} else if p.expectBody(`"else"`) {
+			falseCase = p.parseBlockExpr()
+		}
 		if falseCase == nil {
 			return nil
 		}
 
-		return &ast.IfExpr{Condition: condition, TrueCase: trueCase, FalseCase: falseCase}
+		return &ast.IfExpr{If: pos, Condition: condition, TrueCase: trueCase, FalseCase: falseCase}
 	}
 
-	return &ast.IfExpr{Condition: condition, TrueCase: trueCase}
+	return &ast.IfExpr{If: pos, Condition: condition, TrueCase: trueCase}
 }
 
 func (p *Parser) parseWhileExpr() ast.Expr {
+	pos := p.pos
 	p.advance()
 
-	condition := p.parseExpr(LOWEST)
+	condition := p.parseClause()
 	if condition == nil {
 		return nil
 	}
 
-	body := p.parseExpr(LOWEST)
+	if !p.expectBody("while condition") {
+		return nil
+	}
+
+	body := p.parseBlockExpr()
 	if body == nil {
 		return nil
 	}
 
-	return &ast.WhileExpr{Condition: condition, Body: body}
+	return &ast.WhileExpr{While: pos, Condition: condition, Body: body}
 }
 
-func (p *Parser) parseFuncLit() ast.Expr {
+func (p *Parser) parseForExpr() ast.Expr {
+	pos := p.pos
 	p.advance()
 
-	if !p.expect(token.LPAREN) {
+	if p.tok != token.IDENT {
+		p.unexpected("expected name after %q", token.FOR)
 		return nil
 	}
+	name := &ast.Ident{NamePos: p.pos, Value: p.lit}
 	p.advance()
 
-	params := p.parseFuncParams()
-	if params == nil {
+	if p.tok != token.IN {
+		p.unexpected("expected %q after %q", token.IN, "for "+name.Value)
 		return nil
 	}
+	p.advance()
 
-	if !p.expect(token.RPAREN) {
+	iter := p.parseClause()
+	if iter == nil {
+		return nil
+	}
+
+	if !p.expectBody("for clause") {
 		return nil
 	}
-	p.advance()
 
 	body := p.parseBlockExpr()
 	if body == nil {
 		return nil
 	}
 
-	return &ast.FuncLit{Params: params, Body: body}
+	return &ast.ForExpr{For: pos, Var: name, Iter: iter, Body: body}
 }
 
-func (p *Parser) parseFuncParams() []*ast.Ident {
-	params := []*ast.Ident{}
+func (p *Parser) parseMatchExpr() ast.Expr {
+	pos := p.pos
+	p.advance()
 
-	if p.tok == token.RPAREN {
-		return params
+	subject := p.parseClause()
+	if subject == nil {
+		return nil
 	}
 
-	if !p.expect(token.IDENT) {
+	if !p.expectBody("match subject") {
 		return nil
 	}
-	params = append(params, &ast.Ident{Value: p.lit})
 	p.advance()
+	defer p.allowStructLits()()
+
+	arms := []*ast.MatchArm{}
+	for p.tok != token.RBRACE {
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		arms = append(arms, arm)
+
+		if p.tok == token.COMMA || p.tok == token.SEMI {
+			p.advance()
+		} else if p.tok != token.RBRACE {
+			p.unexpected("expected %q or %q after match arm", token.COMMA, token.RBRACE)
+			return nil
+		}
+	}
+	p.advance()
+
+	return &ast.MatchExpr{Match: pos, Subject: subject, Arms: arms}
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	pattern := p.parsePattern()
+	if pattern == nil || !p.checkBindings(pattern) {
+		return nil
+	}
+
+	var guard ast.Expr
+	if p.tok == token.IF {
+		p.advance()
+
+		if guard = p.parseExpr(LOWEST); guard == nil {
+			return nil
+		}
+	}
 
+	if p.tok != token.FATARROW {
+		p.unexpected("expected %q in match arm", token.FATARROW)
+		return nil
+	}
+	arrow := p.pos
+	p.advance()
+
+	body := p.parseExpr(LOWEST)
+	if body == nil {
+		return nil
+	}
+
+	return &ast.MatchArm{Pattern: pattern, Guard: guard, Arrow: arrow, Body: body}
+}
+
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.tok {
+	case token.IDENT:
+		name := &ast.Ident{NamePos: p.pos, Value: p.lit}
+		p.advance()
+
+		if name.Value == "_" {
+			return &ast.WildcardPattern{Underscore: name.NamePos}
+		}
+		return &ast.BindPattern{Name: name}
+	case token.INT:
+		return &ast.LitPattern{Value: p.parseIntLit()}
+	case token.STRING:
+		return &ast.LitPattern{Value: p.parseStringLit()}
+	case token.SUB:
+		pos := p.pos
+		p.advance()
+
+		if p.tok != token.INT {
+			p.unexpected("expected integer after %q in pattern", token.SUB)
+			return nil
+		}
+		lit := &ast.IntLit{ValuePos: pos, Value: "-" + p.lit}
+		p.advance()
+		return &ast.LitPattern{Value: lit}
+	case token.TRUE, token.FALSE:
+		return &ast.LitPattern{Value: p.parseBoolLit()}
+	case token.NULL:
+		return &ast.LitPattern{Value: p.parseNullLit()}
+	case token.LPAREN:
+		return p.parseTuplePattern()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	}
+
+	p.unexpected("expected pattern")
+	return nil
+}
+
+// parseTuplePattern parses (p, q) and (p,). Like in expressions, (p)
+// without a comma is just p.
+func (p *Parser) parseTuplePattern() ast.Pattern {
+	pos := p.pos
+	p.advance()
+
+	first := p.parsePattern()
+	if first == nil {
+		return nil
+	}
+	if p.tok == token.RPAREN {
+		p.advance()
+		return first
+	}
+
+	elems := []ast.Pattern{first}
 	for p.tok == token.COMMA {
 		p.advance()
 
 		if p.tok == token.RPAREN {
 			break
 		}
+		if p.tok == token.ELLIPSIS {
+			p.errorf("rest patterns are only allowed in array patterns")
+			return nil
+		}
+
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		elems = append(elems, elem)
+	}
+
+	if p.tok != token.RPAREN {
+		p.unexpected("expected %q or %q in tuple pattern", token.COMMA, token.RPAREN)
+		return nil
+	}
+	p.advance()
+
+	return &ast.TuplePattern{Lparen: pos, Elems: elems}
+}
+
+// parseArrayPattern parses [p, q] and [p, ...rest], where the rest
+// pattern must come last.
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Lbrack: p.pos, Elems: []ast.Pattern{}}
+	p.advance()
+
+	for p.tok != token.RBRACKET {
+		if pattern.Rest != nil {
+			p.unexpected("expected %q after rest pattern", token.RBRACKET)
+			return nil
+		}
 
-		if !p.expect(token.IDENT) {
+		if p.tok == token.ELLIPSIS {
+			pattern.Rest = &ast.RestPattern{Ellipsis: p.pos}
+			p.advance()
+
+			if p.tok == token.IDENT {
+				if p.lit != "_" {
+					pattern.Rest.Name = &ast.Ident{NamePos: p.pos, Value: p.lit}
+				}
+				p.advance()
+			}
+		} else {
+			elem := p.parsePattern()
+			if elem == nil {
+				return nil
+			}
+			pattern.Elems = append(pattern.Elems, elem)
+		}
+
+		if p.tok == token.COMMA {
+			p.advance()
+		} else if p.tok != token.RBRACKET {
+			p.unexpected("expected %q or %q in array pattern", token.COMMA, token.RBRACKET)
 			return nil
 		}
-		params = append(params, &ast.Ident{Value: p.lit})
+	}
+	p.advance()
+
+	return pattern
+}
+
+// parseBindingPattern parses the pattern of a let statement or function
+// parameter. These must match any value of the right shape, so literal
+// patterns are not allowed.
+func (p *Parser) parseBindingPattern() ast.Pattern {
+	pattern := p.parsePattern()
+	if pattern == nil {
+		return nil
+	}
+
+	var lit *ast.LitPattern
+	ast.Inspect(pattern, func(n ast.Node) bool {
+		if n, ok := n.(*ast.LitPattern); ok && lit == nil {
+			lit = n
+		}
+		return lit == nil
+	})
+	if lit != nil {
+		p.err = &diag.Diagnostic{
+			Pos:   lit.Pos(),
+			Len:   diag.Span(lit.String()),
+			Msg:   fmt.Sprintf("cannot bind to literal %s", lit),
+			Hints: []string{"literal patterns can only be used in match arms"},
+		}
+		return nil
+	}
+
+	if !p.checkBindings(pattern) {
+		return nil
+	}
+	return pattern
+}
+
+// checkBindings reports names bound more than once by pattern.
+func (p *Parser) checkBindings(pattern ast.Pattern) bool {
+	return p.checkNames(ast.PatternNames(pattern), "pattern "+pattern.String())
+}
+
+// checkNames reports the names that occur more than once in names, which
+// are bound by context.
+func (p *Parser) checkNames(names []*ast.Ident, context string) bool {
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name.Value] {
+			p.err = &diag.Diagnostic{
+				Pos: name.NamePos,
+				Len: diag.Span(name.Value),
+				Msg: fmt.Sprintf("%s bound more than once in %s", name.Value, context),
+			}
+			return false
+		}
+		seen[name.Value] = true
+	}
+	return true
+}
+
+func (p *Parser) parseFuncLit() ast.Expr {
+	pos := p.pos
+	p.advance()
+
+	if p.tok != token.LPAREN {
+		d := p.unexpected("expected %q after %q", token.LPAREN, token.FUNC)
+		if d != nil && p.tok == token.IDENT {
+			d.Hints = append(d.Hints, fmt.Sprintf("functions are unnamed; bind them with let %s = func(...) { ... }", p.lit))
+		}
+		return nil
+	}
+	p.advance()
+
+	lit := &ast.FuncLit{Func: pos}
+	if !p.parseFuncParams(lit) {
+		return nil
+	}
+	p.advance()
+
+	if p.tok == token.ARROW {
 		p.advance()
+
+		if lit.Result = p.parseType(); lit.Result == nil {
+			return nil
+		}
+	}
+
+	if p.tok != token.LBRACE {
+		p.unexpected("expected %q before function body", token.LBRACE)
+		return nil
+	}
+
+	body := p.parseBlockExpr()
+	if body == nil {
+		return nil
 	}
 
-	return params
+	lit.Body = body
+	return lit
+}
+
+// parseFuncParams parses the parameters of lit up to the closing
+// parenthesis. Parameters with a default value must follow those without,
+// and a variadic ...rest parameter comes last.
+func (p *Parser) parseFuncParams(lit *ast.FuncLit) bool {
+	lit.Params = []*ast.Param{}
+
+	for p.tok != token.RPAREN {
+		if lit.Rest != nil {
+			p.unexpected("expected %q after variadic parameter", token.RPAREN)
+			return false
+		}
+
+		if p.tok == token.ELLIPSIS {
+			lit.Rest = &ast.RestPattern{Ellipsis: p.pos}
+			p.advance()
+
+			if p.tok == token.IDENT {
+				if p.lit != "_" {
+					lit.Rest.Name = &ast.Ident{NamePos: p.pos, Value: p.lit}
+				}
+				p.advance()
+			}
+		} else {
+			param := p.parseParam()
+			if param == nil {
+				return false
+			}
+			if n := len(lit.Params); param.Default == nil && n > 0 && lit.Params[n-1].Default != nil {
+				p.err = &diag.Diagnostic{
+					Pos: param.Pos(),
+					Len: diag.Span(param.Pattern.String()),
+					Msg: fmt.Sprintf("missing default value for parameter %s after parameter with default", param.Pattern),
+				}
+				return false
+			}
+			lit.Params = append(lit.Params, param)
+		}
+
+		if p.tok == token.COMMA {
+			p.advance()
+		} else if p.tok != token.RPAREN {
+			p.unexpected("expected %q or %q in parameter list", token.COMMA, token.RPAREN)
+			return false
+		}
+	}
+
+	var names []*ast.Ident
+	for _, param := range lit.Params {
+		names = append(names, ast.PatternNames(param.Pattern)...)
+	}
+	if lit.Rest != nil && lit.Rest.Name != nil {
+		names = append(names, lit.Rest.Name)
+	}
+	return p.checkNames(names, "parameter list")
+}
+
+// parseParam parses a parameter name or a pattern destructuring the
+// argument, followed by an optional = default.
+func (p *Parser) parseParam() *ast.Param {
+	if p.tok != token.IDENT && p.tok != token.LPAREN && p.tok != token.LBRACKET {
+		p.unexpected("expected parameter name or pattern")
+		return nil
+	}
+	pattern := p.parseBindingPattern()
+	if pattern == nil {
+		return nil
+	}
+
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+
+	param := &ast.Param{Pattern: pattern, Type: typ}
+	if p.tok == token.ASSIGN {
+		p.advance()
+
+		if param.Default = p.parseExpr(LOWEST); param.Default == nil {
+			return nil
+		}
+	}
+	return param
+}
+
+// parseAnnotation parses an optional : Type. ok is false if there is an
+// annotation that does not parse.
+func (p *Parser) parseAnnotation() (typ ast.TypeExpr, ok bool) {
+	if p.tok != token.COLON {
+		return nil, true
+	}
+	p.advance()
+
+	typ = p.parseType()
+	return typ, typ != nil
+}
+
+// parseType parses a type: a name, [T], (T, U), or func(T, U) -> R. Like
+// in expressions, (T) without a comma is just T.
+func (p *Parser) parseType() ast.TypeExpr {
+	switch p.tok {
+	case token.IDENT, token.NULL:
+		name := &ast.Ident{NamePos: p.pos, Value: p.tok.String()}
+		if p.tok == token.IDENT {
+			name.Value = p.lit
+		}
+		p.advance()
+		return &ast.NamedType{Name: name}
+
+	case token.LBRACKET:
+		pos := p.pos
+		p.advance()
+
+		elem := p.parseType()
+		if elem == nil {
+			return nil
+		}
+		if p.tok != token.RBRACKET {
+			p.unexpected("expected %q after array element type", token.RBRACKET)
+			return nil
+		}
+		p.advance()
+		return &ast.ArrayType{Lbrack: pos, Elem: elem}
+
+	case token.LPAREN:
+		pos := p.pos
+		p.advance()
+
+		elems, comma := p.parseTypeList()
+		if elems == nil {
+			return nil
+		}
+		if p.tok != token.RPAREN {
+			p.unexpected("expected %q or %q in tuple type", token.COMMA, token.RPAREN)
+			return nil
+		}
+		p.advance()
+
+		if len(elems) == 0 {
+			p.err = &diag.Diagnostic{Pos: pos, Len: 2, Msg: "empty tuple type"}
+			return nil
+		}
+		if len(elems) == 1 && !comma {
+			return elems[0]
+		}
+		return &ast.TupleType{Lparen: pos, Elems: elems}
+
+	case token.FUNC:
+		typ := &ast.FuncType{Func: p.pos}
+		p.advance()
+
+		if p.tok != token.LPAREN {
+			p.unexpected("expected %q after %q in type", token.LPAREN, token.FUNC)
+			return nil
+		}
+		p.advance()
+
+		if typ.Params, _ = p.parseTypeList(); typ.Params == nil {
+			return nil
+		}
+		if p.tok != token.RPAREN {
+			p.unexpected("expected %q or %q in parameter types", token.COMMA, token.RPAREN)
+			return nil
+		}
+		p.advance()
+
+		if p.tok == token.ARROW {
+			p.advance()
+
+			if typ.Result = p.parseType(); typ.Result == nil {
+				return nil
+			}
+		}
+		return typ
+	}
+
+	p.unexpected("expected type")
+	return nil
+}
+
+// parseTypeList parses a comma-separated list of types up to a closing
+// parenthesis. The list may be empty; comma reports whether it ends with
+// a comma.
+func (p *Parser) parseTypeList() (types []ast.TypeExpr, comma bool) {
+	types = []ast.TypeExpr{}
+
+	for p.tok != token.RPAREN {
+		typ := p.parseType()
+		if typ == nil {
+			return nil, false
+		}
+		types = append(types, typ)
+
+		if comma = p.tok == token.COMMA; !comma {
+			break
+		}
+		p.advance()
+	}
+
+	return types, comma
 }
 
 func (p *Parser) advance() {
 	p.tok, p.lit = p.l.NextToken()
+	p.pos = p.l.Pos()
 }
 
 func (p *Parser) registerPrefix(tok token.Token, fn prefixParseFn) {
@@ -517,8 +1406,76 @@ func (p *Parser) curPrecedence() int {
 
 func (p *Parser) expect(tok token.Token) bool {
 	if p.tok != tok {
-		p.err = fmt.Errorf("expected %q, got %q", tok, p.tok)
+		p.unexpected("expected %q", tok)
 		return false
 	}
 	return true
 }
+
+// expectBody checks that the block body following context starts here.
+// Requiring the brace keeps "if c (x)" from reading as a call of c.
+func (p *Parser) expectBody(context string) bool {
+	if p.tok == token.LBRACE {
+		return true
+	}
+
+	d := p.unexpected("expected %q after %s", token.LBRACE, context)
+	if d != nil && p.tok == token.SEMI {
+		d.Hints = append(d.Hints, "put the opening brace on the same line")
+	}
+	return false
+}
+
+// expectedExpr lists the tokens that can start an expression.
+func (p *Parser) expectedExpr() string {
+	toks := make([]token.Token, 0, len(p.prefixParseFns))
+	for tok := range p.prefixParseFns {
+		toks = append(toks, tok)
+	}
+	sort.Slice(toks, func(i, j int) bool { return toks[i] < toks[j] })
+
+	names := make([]string, len(toks))
+	for i, tok := range toks {
+		names[i] = fmt.Sprintf("%q", tok)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// unexpected reports that the current token is not what the parser
+// expected. It returns nil if the token was rejected by the lexer.
+func (p *Parser) unexpected(format string, args ...any) *diag.Diagnostic {
+	if p.tok == token.ILLEGAL {
+		p.illegal()
+		return nil
+	}
+	return p.errorf("%s, got %s", fmt.Sprintf(format, args...), p.found())
+}
+
+func (p *Parser) found() string {
+	switch p.tok {
+	case token.IDENT, token.INT:
+		return fmt.Sprintf("%s %q", p.tok, p.lit)
+	case token.STRING:
+		return fmt.Sprintf("%s %s", p.tok, p.lit)
+	default:
+		return fmt.Sprintf("%q", p.tok)
+	}
+}
+
+func (p *Parser) errorf(format string, args ...any) *diag.Diagnostic {
+	d := &diag.Diagnostic{Pos: p.pos, Len: diag.Span(p.lit), Msg: fmt.Sprintf(format, args...)}
+	p.err = d
+	return d
+}
+
+func (p *Parser) illegal() {
+	var lerr *lexer.Error
+	if errors.As(p.l.Error(), &lerr) {
+		p.err = &diag.Diagnostic{Pos: lerr.Pos, Len: 1, Msg: lerr.Msg}
+		return
+	}
+	p.errorf("%s", p.l.Error())
+}
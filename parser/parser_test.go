@@ -1,7 +1,12 @@
 package parser
 
 import (
+	"errors"
+	"oasis/ast"
+	"oasis/diag"
 	"oasis/lexer"
+	"oasis/token"
+	"reflect"
 	"testing"
 )
 
@@ -15,12 +20,14 @@ func TestExpressions(t *testing.T) {
 		{"-1", "(-1)"},
 		{"~2", "(~2)"},
 		{"!false", "(!false)"},
+		{"true == !null", "(true == (!null))"},
 		{"(10 + 5)", "(10 + 5)"},
 		{"a = 10", "(a = 10)"},
 		{"a += 10", "(a += 10)"},
 		{"a -= 10", "(a -= 10)"},
 		{"a *= 10", "(a *= 10)"},
 		{"a /= 10", "(a /= 10)"},
+		{"a %= 10", "(a %= 10)"},
 		{"a &= 10", "(a &= 10)"},
 		{"a |= 10", "(a |= 10)"},
 		{"a ^= 10", "(a ^= 10)"},
@@ -42,8 +49,57 @@ func TestExpressions(t *testing.T) {
 		{"{ 10 }", "{ 10; }"},
 		{"if true { 1 }", "if true { 1; }"},
 		{"if true { 1 } else { 0 }", "if true { 1; } else { 0; }"},
+		{"if a { 1 } else if b { 2 } else { 3 }", "if a { 1; } else if b { 2; } else { 3; }"},
+		{"if a { 1 } else if b { 2 }", "if a { 1; } else if b { 2; }"},
 		{"while true { 10 }", "while true { 10; }"},
+		{"0..10", "(0 .. 10)"},
+		{"0..=n", "(0 ..= n)"},
+		{"a + 1..b * 2", "((a + 1) .. (b * 2))"},
+		{"x = 0..a || b", "(x = (0 .. (a || b)))"},
+		{"for x in xs { x }", "for x in xs { x; }"},
+		{"for i in 0..n + 1 { f(i) }", "for i in (0 .. (n + 1)) { f(i, ); }"},
+		{"match x {}", "match x { }"},
+		{"match x { 1 => a, -2 => b, true => c, null => d, _ => e }", "match x { 1 => a, -2 => b, true => c, null => d, _ => e, }"},
+		{"match x { n if n > 0 => n, n => -n }", "match x { n if (n > 0) => n, n => (-n), }"},
+		{"match x {\n\t0 => { a }\n\t_ => b + 1,\n}", "match x { 0 => { a; }, _ => (b + 1), }"},
+		{"match f(x) { _ => 1 } + 1", "(match f(x, ) { _ => 1, } + 1)"},
 		{"func() { 10 }", "func() { 10; }"},
+		{`"a\tb"`, `"a\tb"`},
+		{`"say \"hi\""`, `"say \"hi\""`},
+		{"a.b", "a.b"},
+		{"m.f(1).g + 1", "(m.f(1, ).g + 1)"},
+		{"-m.x", "(-m.x)"},
+		{`match s { "a" => 1, _ => 2 }`, `match s { "a" => 1, _ => 2, }`},
+		{"(1, a + 2)", "(1, (a + 2))"},
+		{"(1,)", "(1,)"},
+		{"[]", "[]"},
+		{"[1, [2], (3, 4),]", "[1, [2], (3, 4)]"},
+		{"match p { (0, y) => y, [x, ...] => x, ((a, b),) => a }", "match p { (0, y) => y, [x, ...] => x, ((a, b),) => a, }"},
+		{"func((a, b), [c, ...d]) { a }", "func((a, b), [c, ...d], ) { a; }"},
+		{"func(a, b = 10, ...rest) { a }", "func(a, b = 10, ...rest, ) { a; }"},
+		{"func(a = b + 1, (c, d) = (1, 2),) { a }", "func(a = (b + 1), (c, d) = (1, 2), ) { a; }"},
+		{"func(...) { 0 }", "func(..., ) { 0; }"},
+		{"f(...xs)", "f(...xs, )"},
+		{"f(1, ...g(x), 2, ...(3, 4))", "f(1, ...g(x, ), 2, ...(3, 4), )"},
+		{"connect(host, port: 8080, tls: a == b,)", "connect(host, port: 8080, tls: (a == b), )"},
+		{"f(a: g(b: 1))", "f(a: g(b: 1, ), )"},
+		{"func(a: int, b: str) -> bool { a }", "func(a: int, b: str, ) -> bool { a; }"},
+		{"func((a, b): (int, [str]), n: int = 1,) -> null { a }", "func((a, b): (int, [str]), n: int = 1, ) -> null { a; }"},
+		{"func(f: func(int, str) -> bool, g: func()) { f }", "func(f: func(int, str) -> bool, g: func(), ) { f; }"},
+		{"func() -> (int) { 1 }", "func() -> int { 1; }"},
+		{"func() -> (int,) { (1,) }", "func() -> (int,) { (1,); }"},
+		{"Point { x: 1, y: a + 2 }", "(Point { x: 1, y: (a + 2) })"},
+		{"Point {}", "(Point {})"},
+		{"Point {\n\tx: 1\n\ty: 2\n}", "(Point { x: 1, y: 2 })"},
+		{"geo.Point { x: 1, y: 2, }.x", "(geo.Point { x: 1, y: 2 }).x"},
+		{"Line { from: Point { x: 0, y: 0 }, to: p }", "(Line { from: (Point { x: 0, y: 0 }), to: p })"},
+		{"p.x = p.y + 1", "(p.x = (p.y + 1))"},
+		{"if p == (Point { x: 1 }) { 1 }", "if (p == (Point { x: 1 })) { 1; }"},
+		{"if f(Point { x: 1 }) { 1 }", "if f((Point { x: 1 }), ) { 1; }"},
+		{"for p in [Point {}] { p }", "for p in [(Point {})] { p; }"},
+		{"match p { q => Point { x: q } }", "match p { q => (Point { x: q }), }"},
+		{"if (P {}).x { 1 }", "if (P {}).x { 1; }"},
+		{"match (P {}) { _ => 1 }", "match (P {}) { _ => 1, }"},
 	}
 
 	for i, tt := range tests {
@@ -67,6 +123,19 @@ func TestLetStatements(t *testing.T) {
 		output string
 	}{
 		{"let a = 10", "let a = 10;"},
+		{"let mut total = 0", "let mut total = 0;"},
+		{"let (a, b) = (1, 2)", "let (a, b) = (1, 2);"},
+		{"let ((a, b),) = ((1, 2),)", "let ((a, b),) = ((1, 2),);"},
+		{"let (a) = b", "let a = b;"},
+		{"let [x, _, ...rest] = [1, 2, 3, 4]", "let [x, _, ...rest] = [1, 2, 3, 4];"},
+		{"let [first, ...] = xs", "let [first, ...] = xs;"},
+		{"let [..._] = xs", "let [...] = xs;"},
+		{"let [] = [\n]", "let [] = [];"},
+		{"let mut [a, (b, c)] = xs", "let mut [a, (b, c)] = xs;"},
+		{"let x: int = 1", "let x: int = 1;"},
+		{"let mut xs: [[int]] = []", "let mut xs: [[int]] = [];"},
+		{"let (a, b): (int, str) = p", "let (a, b): (int, str) = p;"},
+		{"let f: func(int) -> func() -> any = g", "let f: func(int) -> func() -> any = g;"},
 	}
 
 	for i, tt := range tests {
@@ -84,6 +153,86 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+		name   string
+	}{
+		{`import "math"`, `import "math";`, "math"},
+		{`import "./lib/strings.oasis"`, `import "./lib/strings.oasis";`, "strings"},
+		{`import "../shapes/circle"`, `import "../shapes/circle";`, "circle"},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		stmt := program.Stmts[0].(*ast.ImportStmt)
+		if stmt.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, stmt.String())
+		}
+		if stmt.Name.Value != tt.name {
+			t.Fatalf("tests[%d]: expected name %q, got %q", i, tt.name, stmt.Name.Value)
+		}
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"const max = 1 << 10", "const max = (1 << 10);"},
+		{"const name: str = \"oasis\"", "const name: str = \"oasis\";"},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		stmt := p.parseConstStmt()
+		if stmt == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		if stmt.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, stmt.String())
+		}
+	}
+}
+
+func TestStructStatements(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"struct Empty {}", "struct Empty {};"},
+		{"struct Point { x, y }", "struct Point { x, y };"},
+		{"struct Point { x: int, y: int, }", "struct Point { x: int, y: int };"},
+		{"struct Config {\n\thost: str\n\tports: [int]\n\tnext\n}", "struct Config { host: str, ports: [int], next };"},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		stmt := p.parseStructStmt()
+		if stmt == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		if stmt.String() != tt.output {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.output, stmt.String())
+		}
+	}
+}
+
 func TestContinueStatements(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -154,3 +303,282 @@ func TestReturnStatements(t *testing.T) {
 		}
 	}
 }
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"let = 10", `1:5: expected name or pattern after "let", got "="`},
+		{"let 1 = 1", `1:5: expected name or pattern after "let", got INT "1"`},
+		{"let (a, 1) = x", `1:9: cannot bind to literal 1`},
+		{"let [a, ...b, c] = x", `1:15: expected "]" after rest pattern, got IDENT "c"`},
+		{"let (a, ...b) = x", `1:9: rest patterns are only allowed in array patterns`},
+		{"let (a, b = x", `1:11: expected "," or ")" in tuple pattern, got "="`},
+		{"let [a b] = x", `1:8: expected "," or "]" in array pattern, got IDENT "b"`},
+		{"let (a, [b, a]) = x", `1:13: a bound more than once in pattern (a, [b, a])`},
+		{"match x { [n, n] => n }", `1:15: n bound more than once in pattern [n, n]`},
+		{"func(a, a) { a }", `1:9: a bound more than once in parameter list`},
+		{"func(x, ...x) { x }", `1:12: x bound more than once in parameter list`},
+		{"func((a, b), [c, b]) { a }", `1:18: b bound more than once in parameter list`},
+		{"(1, 2", `1:6: expected "," or ")" in tuple, got ";"`},
+		{"[1 2]", `1:4: expected "," or "]" in array, got INT "2"`},
+		{"let a = 10\nlet b 2", `2:7: expected "=" after "let b", got INT "2"`},
+		{"f(1, 2", `1:7: expected "," or ")" in argument list, got ";"`},
+		{"let é = \xff", `1:9: invalid UTF-8 encoding "\xff"`},
+		{"a + }", `1:5: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got "}"`},
+		{"let b = a * let", `1:13: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got "let"`},
+		{"if x == 1 return 2", `1:11: expected "{" after if condition, got "return"`},
+		{"if x 1", `1:6: expected "{" after if condition, got INT "1"`},
+		{"if c 1 + 2", `1:6: expected "{" after if condition, got INT "1"`},
+		{"if x { 1 } else 2", `1:17: expected "{" after "else", got INT "2"`},
+		{"while x y += 1", `1:9: expected "{" after while condition, got IDENT "y"`},
+		{"if x\n{ 1 }", `1:5: expected "{" after if condition, got ";"`},
+		{"if x { 1 } else return", `1:17: expected "{" after "else", got "return"`},
+		{"while x < 10 break", `1:14: expected "{" after while condition, got "break"`},
+		{"if c (x)", `1:9: expected "{" after if condition, got ";"`},
+		{"while x (y)", `1:12: expected "{" after while condition, got ";"`},
+		{"func add(a, b) { a + b }", `1:6: expected "(" after "func", got IDENT "add"`},
+		{"func(a, 1) { a }", `1:9: expected parameter name or pattern, got INT "1"`},
+		{"func((a, \"b\")) { a }", `1:10: cannot bind to literal "b"`},
+		{"func(a b) { a }", `1:8: expected "," or ")" in parameter list, got IDENT "b"`},
+		{"func(a = 1, b) { a }", `1:13: missing default value for parameter b after parameter with default`},
+		{"func(...rest, a) { a }", `1:15: expected ")" after variadic parameter, got IDENT "a"`},
+		{"func(...rest = 1) { a }", `1:14: expected "," or ")" in parameter list, got "="`},
+		{"func(a = ) { a }", `1:10: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got ")"`},
+		{"f(...)", `1:6: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got ")"`},
+		{"f(a: 1, 2)", `1:9: positional argument after named argument`},
+		{"f(a: 1, ...xs)", `1:9: positional argument after named argument`},
+		{"f(a: 1, b: 2, a: 3)", `1:15: duplicate argument a`},
+		{"f(1: 2)", `1:4: expected "," or ")" in argument list, got ":"`},
+		{"f(a:)", `1:5: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got ")"`},
+		{"...xs", `1:1: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got "..."`},
+		{"func(a) a", `1:9: expected "{" before function body, got IDENT "a"`},
+		{"if ok { x: 1 }", `1:10: expected ";", got ":"`},
+		{"Point { x 1 }", `1:11: expected ":" after field name x, got INT "1"`},
+		{"Point { 1: x }", `1:9: expected field name in struct literal, got INT "1"`},
+		{"Point { x: 1 y: 2 }", `1:14: expected "," or "}" after field, got IDENT "y"`},
+		{"Point { x: 1, x: 2 }", `1:15: duplicate field x in struct literal`},
+		{"struct { x }", `1:8: expected name after "struct", got "{"`},
+		{"struct Point x", `1:14: expected "{" after "struct Point", got IDENT "x"`},
+		{"struct Point { x, 1 }", `1:19: expected field name in struct Point, got INT "1"`},
+		{"struct Point { x: int, x }", `1:24: duplicate field x in struct Point`},
+		{"struct Point { x: }", `1:19: expected type, got "}"`},
+		{"struct Point { x y }", `1:18: expected "," or "}" after field, got IDENT "y"`},
+		{"struct Point {} 1", `1:17: expected ";", got INT "1"`},
+		{"let x: = 1", `1:8: expected type, got "="`},
+		{"let x: 1 = 1", `1:8: expected type, got INT "1"`},
+		{"let xs: [int = 1", `1:14: expected "]" after array element type, got "="`},
+		{"let p: () = 1", `1:8: empty tuple type`},
+		{"func(a = 1, b: int) { a }", `1:13: missing default value for parameter b after parameter with default`},
+		{"func(a: int b) { a }", `1:13: expected "," or ")" in parameter list, got IDENT "b"`},
+		{"func() -> { 1 }", `1:11: expected type, got "{"`},
+		{"for 1 in xs {}", `1:5: expected name after "for", got INT "1"`},
+		{"for x xs {}", `1:7: expected "in" after "for x", got IDENT "xs"`},
+		{"for x in xs x", `1:13: expected "{" after for clause, got IDENT "x"`},
+		{"0..1..2", `1:5: range bound cannot be a range`},
+		{"match x\n{ _ => 1 }", `1:8: expected "{" after match subject, got ";"`},
+		{"match x { 1 + 2 => 3 }", `1:13: expected "=>" in match arm, got "+"`},
+		{"match x { (1 => 3 }", `1:14: expected "," or ")" in tuple pattern, got "=>"`},
+		{"match x { -a => 3 }", `1:12: expected integer after "-" in pattern, got IDENT "a"`},
+		{"match x { 1 => 2 3 => 4 }", `1:18: expected "," or "}" after match arm, got INT "3"`},
+		{"match x { n if => 1 }", `1:16: expected "IDENT", "INT", "STRING", "-", "~", "!", "(", "{", "[", "if", "while", "for", "match", "func", "true", "false" or "null", got "=>"`},
+		{"a.1", `1:3: expected name after ".", got INT "1"`},
+		{"const 1 = 2", `1:7: expected name after "const", got INT "1"`},
+		{"let mut = 2", `1:9: expected name or pattern after "let mut", got "="`},
+		{"let mut mut = 2", `1:9: expected name or pattern after "let mut", got "mut"`},
+		{"const a 2", `1:9: expected "=" after "const a", got INT "2"`},
+		{`import math`, `1:8: expected module path after "import", got IDENT "math"`},
+		{`import "my-lib"`, `1:8: invalid module name "my-lib"`},
+		{`import "lib/2d"`, `1:8: invalid module name "2d"`},
+		{`if x { import "a" }`, `1:8: imports must be at the top level`},
+		{`"abc`, `1:1: string literal not terminated`},
+		{`x = "\d"`, `1:6: unknown escape sequence \d`},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		if program != nil {
+			t.Fatalf("tests[%d]: expected error, got %q", i, program)
+		}
+
+		if p.Error().Error() != tt.err {
+			t.Fatalf("tests[%d]: expected %q, got %q", i, tt.err, p.Error())
+		}
+	}
+}
+
+func TestErrorHints(t *testing.T) {
+	tests := []struct {
+		input string
+		hint  string
+	}{
+		{"if x\n{ 1 }", "put the opening brace on the same line"},
+		{"while x\n{ 1 }", "put the opening brace on the same line"},
+		{"func add(a, b) { a + b }", "functions are unnamed; bind them with let add = func(...) { ... }"},
+		{"let a\n", "variables must be initialized: let a = ..."},
+		{"const a\n", "constants must be initialized: const a = ..."},
+		{"let mut a\n", "variables must be initialized: let mut a = ..."},
+		{"let (a, b)\n", "variables must be initialized: let (a, b) = ..."},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		if program := p.ParseProgram(); program != nil {
+			t.Fatalf("tests[%d]: expected error, got %q", i, program)
+		}
+
+		var d *diag.Diagnostic
+		if !errors.As(p.Error(), &d) {
+			t.Fatalf("tests[%d]: expected a diagnostic, got %T", i, p.Error())
+		}
+
+		if len(d.Hints) != 1 || d.Hints[0] != tt.hint {
+			t.Fatalf("tests[%d]: expected hint %q, got %q", i, tt.hint, d.Hints)
+		}
+	}
+}
+
+func FuzzParseProgram(f *testing.F) {
+	for _, input := range []string{
+		"a", "1", "-1", "~2", "!false", "(10 + 5)", "a = 10", "a <<= 10", "true && true || false",
+		"1 & 1 | 0 ^ 0", "1 + 1 - 1 * 1 / 1 % 1", "a()", "sum(1, 3)", "{}", "{ 10 }",
+		"if true { 1 }", "if true { 1 } else { 0 }", "while true { 10 }", "func() { 10 }",
+		"let a = 10", "const b = -a", "let mut c = b", "continue", "break", "break 10", "return", "return 10",
+		"let fib = func(n) {\n\tif n < 2 { return n }\n\tfib(n - 1) + fib(n - 2)\n}\nfib(10)",
+		"for i in 0..=10 { i }", "match x {\n\t-1 => a\n\tn if n > 0 => b, _ => c\n}",
+		"import \"lib/m\"\nm.f(\"a\\n\").g", "match s { \"\\\"\" => 1 }",
+		"let (a, [b, ...c]) = (1, [2, 3])", "let f = func((x, y), [z, ...]) { x }\nf((1,), [])",
+		"match p { (0, _) => 1, [x, ...rest] if x > 0 => rest }",
+		"let f = func(a, b = a * 2, ...rest) { b }\nf(1, ...[2, 3])",
+		"connect(host, port: 8080, tls: true)",
+		"struct Point { x: int, y }\nlet p = Point { x: 1, y: 2 }\np.x += p.y\nif p == (Point {}) { geo.Line {} }",
+		"let x: int = 1\nlet f = func(a: [str], b: (int, bool) = (1, true)) -> func(int) -> null { g }",
+		"if (P {}).x { 1 }", "match (P {}) { _ => 1 }", "if c 1 + 2",
+	} {
+		f.Add(input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+
+		program := p.ParseProgram()
+		if program == nil {
+			if p.Error() == nil {
+				t.Fatalf("parse failed without error")
+			}
+			return
+		}
+
+		output := program.String()
+		p = New(lexer.New(output))
+
+		reparsed := p.ParseProgram()
+		if reparsed == nil {
+			t.Fatalf("reparsing %q failed: %s", output, p.Error())
+		}
+
+		if !sameTree(reflect.ValueOf(program), reflect.ValueOf(reparsed)) {
+			t.Fatalf("round trip changed the program: %q became %q", output, reparsed.String())
+		}
+	})
+}
+
+var posType = reflect.TypeOf(token.Pos{})
+
+// sameTree reports whether a and b hold the same syntax tree, ignoring
+// positions.
+func sameTree(a, b reflect.Value) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return sameTree(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if a.Type() == posType {
+			return true
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !sameTree(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !sameTree(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	panic("sameTree: unexpected " + a.Type().String())
+}
+
+func TestPositions(t *testing.T) {
+	input := "let f = func(a) {\n\tif a { return -a }\n\tg(a) + 1\n}"
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	if program == nil {
+		t.Fatal(p.Error())
+	}
+
+	let := program.Stmts[0].(*ast.LetStmt)
+	fn := let.Value.(*ast.FuncLit)
+	body := fn.Body.(*ast.BlockExpr)
+	ifExpr := body.Stmts[0].(*ast.ExprStmt).Expr.(*ast.IfExpr)
+	ret := ifExpr.TrueCase.(*ast.BlockExpr).Stmts[0].(*ast.ReturnStmt)
+	sum := body.Stmts[1].(*ast.ExprStmt).Expr.(*ast.InfixExpr)
+	call := sum.Left.(*ast.CallExpr)
+
+	tests := []struct {
+		node ast.Node
+		pos  token.Pos
+	}{
+		{let, token.Pos{Line: 1, Col: 1}},
+		{let.Pattern, token.Pos{Line: 1, Col: 5}},
+		{fn, token.Pos{Line: 1, Col: 9}},
+		{fn.Params[0], token.Pos{Line: 1, Col: 14}},
+		{body, token.Pos{Line: 1, Col: 17}},
+		{ifExpr, token.Pos{Line: 2, Col: 2}},
+		{ret, token.Pos{Line: 2, Col: 9}},
+		{ret.Value, token.Pos{Line: 2, Col: 16}},
+		{sum, token.Pos{Line: 3, Col: 2}},
+		{call, token.Pos{Line: 3, Col: 2}},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos() != tt.pos {
+			t.Fatalf("tests[%d]: expected %s at %s, got %s", i, tt.node, tt.pos, tt.node.Pos())
+		}
+	}
+
+	if sum.OpPos != (token.Pos{Line: 3, Col: 7}) || call.Lparen != (token.Pos{Line: 3, Col: 3}) {
+		t.Fatalf("wrong operator positions: %s, %s", sum.OpPos, call.Lparen)
+	}
+}
@@ -0,0 +1,29 @@
+package token
+
+import "fmt"
+
+// Pos is a location in the source. Lines and columns start at 1 and
+// columns count runes, not bytes. File is empty for sources that were
+// not read from a named file.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	if !p.IsValid() {
+		if p.File != "" {
+			return p.File
+		}
+		return "-"
+	}
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
@@ -0,0 +1,51 @@
+package token
+
+import "strings"
+
+// Unquote returns the value of a STRING literal as the lexer returns it.
+func Unquote(lit string) string {
+	var buf strings.Builder
+	escaped := false
+	for _, ch := range lit[1 : len(lit)-1] {
+		if escaped {
+			switch ch {
+			case 'n':
+				ch = '\n'
+			case 't':
+				ch = '\t'
+			case 'r':
+				ch = '\r'
+			}
+			escaped = false
+		} else if ch == '\\' {
+			escaped = true
+			continue
+		}
+		buf.WriteRune(ch)
+	}
+	return buf.String()
+}
+
+// Quote returns s as a STRING literal that Unquote turns back into s.
+func Quote(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '"':
+			buf.WriteString(`\"`)
+		default:
+			buf.WriteRune(ch)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
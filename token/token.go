@@ -11,6 +11,7 @@ const (
 
 	IDENT
 	INT
+	STRING
 
 	ASSIGN
 	ADD
@@ -49,22 +50,42 @@ const (
 	GT
 	GTE
 
+	DOT
+	DOTDOT
+	DOTDOT_EQ
+	ELLIPSIS
+	FATARROW
+	ARROW
+
 	COMMA
+	COLON
 	SEMI
 
 	LPAREN
 	RPAREN
 	LBRACE
 	RBRACE
+	LBRACKET
+	RBRACKET
 
+	IMPORT
 	LET
+	MUT
+	CONST
+	STRUCT
 	IF
 	ELSE
 	WHILE
+	FOR
+	IN
+	MATCH
 	CONTINUE
 	BREAK
 	FUNC
 	RETURN
+	TRUE
+	FALSE
+	NULL
 )
 
 var TokenName = map[Token]string{
@@ -72,8 +93,9 @@ var TokenName = map[Token]string{
 	UNEXPECTED: "UNEXPECTED",
 	EOF:        "EOF",
 
-	IDENT: "IDENT",
-	INT:   "INT",
+	IDENT:  "IDENT",
+	INT:    "INT",
+	STRING: "STRING",
 
 	ASSIGN: "=",
 	ADD:    "+",
@@ -112,37 +134,78 @@ var TokenName = map[Token]string{
 	GT:  ">",
 	GTE: ">=",
 
+	DOT:       ".",
+	DOTDOT:    "..",
+	DOTDOT_EQ: "..=",
+	ELLIPSIS:  "...",
+	FATARROW:  "=>",
+	ARROW:     "->",
+
 	COMMA: ",",
+	COLON: ":",
 	SEMI:  ";",
 
-	LPAREN: "(",
-	RPAREN: ")",
-	LBRACE: "{",
-	RBRACE: "}",
+	LPAREN:   "(",
+	RPAREN:   ")",
+	LBRACE:   "{",
+	RBRACE:   "}",
+	LBRACKET: "[",
+	RBRACKET: "]",
 
+	IMPORT:   "import",
 	LET:      "let",
+	MUT:      "mut",
+	CONST:    "const",
+	STRUCT:   "struct",
 	IF:       "if",
 	ELSE:     "else",
 	WHILE:    "while",
+	FOR:      "for",
+	IN:       "in",
+	MATCH:    "match",
 	CONTINUE: "continue",
 	BREAK:    "break",
 	FUNC:     "func",
 	RETURN:   "return",
+	TRUE:     "true",
+	FALSE:    "false",
+	NULL:     "null",
 }
 
 func (tok Token) String() string {
 	return TokenName[tok]
 }
 
+// IsAssignment reports whether tok is = or a compound assignment
+// operator such as +=.
+func (tok Token) IsAssignment() bool {
+	switch tok {
+	case ASSIGN, ADD_ASSIGN, SUB_ASSIGN, MUL_ASSIGN, DIV_ASSIGN, MOD_ASSIGN,
+		AND_ASSIGN, OR_ASSIGN, XOR_ASSIGN, LSHIFT_ASSIGN, RSHIFT_ASSIGN:
+		return true
+	}
+	return false
+}
+
 var keywords = map[string]Token{
+	"import":   IMPORT,
 	"let":      LET,
+	"mut":      MUT,
+	"const":    CONST,
+	"struct":   STRUCT,
 	"if":       IF,
 	"else":     ELSE,
 	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"match":    MATCH,
 	"continue": CONTINUE,
 	"break":    BREAK,
 	"func":     FUNC,
 	"return":   RETURN,
+	"true":     TRUE,
+	"false":    FALSE,
+	"null":     NULL,
 }
 
 func LookupIdent(ident string) Token {
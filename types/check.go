@@ -0,0 +1,760 @@
+package types
+
+import (
+	"fmt"
+	"oasis/ast"
+	"oasis/diag"
+	"oasis/token"
+	"sort"
+)
+
+// Check reports the type errors in prog: values that do not match the
+// type they are annotated with, and operations that fail for every value
+// of the types of their operands.
+func Check(prog *ast.Program) []*diag.Diagnostic {
+	vars, structs := resolve(prog)
+	c := &checker{vars: vars}
+
+	// Struct types are known before any code runs, so their fields are
+	// filled in first.
+	for _, stmt := range structs {
+		st := vars[stmt.Name].Struct
+		for _, field := range stmt.Fields {
+			t := Type(Any)
+			if field.Type != nil {
+				t = c.annotation(field.Type)
+			}
+			st.Fields = append(st.Fields, Field{Name: field.Name.Value, Type: t})
+		}
+	}
+
+	for _, stmt := range prog.Stmts {
+		c.stmt(stmt)
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Pos, c.diags[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+	return c.diags
+}
+
+type checker struct {
+	vars  map[*ast.Ident]*Var
+	fn    *funcInfo
+	diags []*diag.Diagnostic
+}
+
+// funcInfo describes the function whose body is being checked. result
+// is the annotated result type; without one, returns collects the types
+// of the returned values.
+type funcInfo struct {
+	result  Type
+	returns Type
+}
+
+// expected is the type the context of an expression requires of it.
+type expected struct {
+	typ     Type
+	context string
+
+	// result is set for the body of a function.
+	result bool
+	note   *diag.Note
+}
+
+// elemTypes returns the tuple type e expects, if any.
+func (e *expected) elemTypes() (*Tuple, bool) {
+	if e == nil {
+		return nil, false
+	}
+	t, ok := e.typ.(*Tuple)
+	return t, ok
+}
+
+var assignOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN:    token.ADD,
+	token.SUB_ASSIGN:    token.SUB,
+	token.MUL_ASSIGN:    token.MUL,
+	token.DIV_ASSIGN:    token.DIV,
+	token.MOD_ASSIGN:    token.MOD,
+	token.AND_ASSIGN:    token.AND,
+	token.OR_ASSIGN:     token.OR,
+	token.XOR_ASSIGN:    token.XOR,
+	token.LSHIFT_ASSIGN: token.LSHIFT,
+	token.RSHIFT_ASSIGN: token.RSHIFT,
+}
+
+func (c *checker) errorf(pos token.Pos, n int, format string, args ...any) *diag.Diagnostic {
+	d := &diag.Diagnostic{Pos: pos, Len: n, Msg: fmt.Sprintf(format, args...)}
+	c.diags = append(c.diags, d)
+	return d
+}
+
+// annotation returns the type denoted by expr, reporting names that do
+// not denote a type. A type that cannot be resolved is any.
+func (c *checker) annotation(expr ast.TypeExpr) Type {
+	t, bad := c.lookupType(expr)
+	switch {
+	case bad == nil:
+		return t
+	case c.vars[bad.Name] != nil:
+		c.errorf(bad.Pos(), diag.Span(bad.Name.Value), "%s is not a type", bad.Name.Value)
+	default:
+		c.errorf(bad.Pos(), diag.Span(bad.Name.Value), "unknown type %s", bad.Name.Value)
+	}
+	return Any
+}
+
+// lookupType returns the type denoted by expr, or the first name in it
+// that does not denote a type.
+func (c *checker) lookupType(expr ast.TypeExpr) (Type, *ast.NamedType) {
+	switch expr := expr.(type) {
+	case *ast.NamedType:
+		if v := c.vars[expr.Name]; v != nil && v.Struct != nil {
+			return v.Struct, nil
+		}
+		if t, ok := predeclared[expr.Name.Value]; ok {
+			return t, nil
+		}
+		return nil, expr
+	case *ast.ArrayType:
+		elem, bad := c.lookupType(expr.Elem)
+		if bad != nil {
+			return nil, bad
+		}
+		return &Array{Elem: elem}, nil
+	case *ast.TupleType:
+		elems := make([]Type, len(expr.Elems))
+		for i, elem := range expr.Elems {
+			var bad *ast.NamedType
+			if elems[i], bad = c.lookupType(elem); bad != nil {
+				return nil, bad
+			}
+		}
+		return &Tuple{Elems: elems}, nil
+	case *ast.FuncType:
+		f := &Func{Params: make([]Param, len(expr.Params)), Result: Any}
+		for i, param := range expr.Params {
+			t, bad := c.lookupType(param)
+			if bad != nil {
+				return nil, bad
+			}
+			f.Params[i] = Param{Type: t}
+		}
+		if expr.Result != nil {
+			var bad *ast.NamedType
+			if f.Result, bad = c.lookupType(expr.Result); bad != nil {
+				return nil, bad
+			}
+		}
+		return f, nil
+	}
+	return Any, nil
+}
+
+// define records t as the type of the variable declared by name. The
+// type of a variable the program assigns to is only known if it is
+// annotated, in which case t is the annotated type.
+func (c *checker) define(name *ast.Ident, t Type) {
+	v := c.vars[name]
+	switch {
+	case v == nil:
+	case v.Annotation == nil && (v.Assigned || t == never):
+		v.typ = Any
+	default:
+		v.typ = t
+	}
+}
+
+// destructure defines the names bound by pattern to the parts of a value
+// of type t. If strict is set the value must match the pattern; in match
+// arms a value that does not only skips the arm.
+func (c *checker) destructure(pattern ast.Pattern, t Type, strict bool) {
+	switch pattern := pattern.(type) {
+	case *ast.BindPattern:
+		c.define(pattern.Name, t)
+	case *ast.TuplePattern:
+		tuple, ok := t.(*Tuple)
+		if ok && len(tuple.Elems) == len(pattern.Elems) {
+			for i, elem := range pattern.Elems {
+				c.destructure(elem, tuple.Elems[i], strict)
+			}
+			return
+		}
+		if strict && ok {
+			c.errorf(pattern.Pos(), diag.Span(pattern.String()), "tuple pattern %s needs %d elements, got %d",
+				pattern, len(pattern.Elems), len(tuple.Elems))
+		} else if strict && t != Any && t != never {
+			c.errorf(pattern.Pos(), diag.Span(pattern.String()), "cannot destructure type %s with tuple pattern %s", t, pattern)
+		}
+		for _, elem := range pattern.Elems {
+			c.destructure(elem, Any, strict)
+		}
+	case *ast.ArrayPattern:
+		elem := Type(Any)
+		if arr, ok := t.(*Array); ok {
+			elem = arr.Elem
+		} else if strict && t != Any && t != never {
+			c.errorf(pattern.Pos(), diag.Span(pattern.String()), "cannot destructure type %s with array pattern %s", t, pattern)
+		}
+		for _, p := range pattern.Elems {
+			c.destructure(p, elem, strict)
+		}
+		if rest := pattern.Rest; rest != nil && rest.Name != nil {
+			c.define(rest.Name, &Array{Elem: elem})
+		}
+	}
+}
+
+func (c *checker) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.ExprStmt:
+		c.expr(stmt.Expr, nil)
+	case *ast.ImportStmt:
+		c.define(stmt.Name, Any)
+	case *ast.StructStmt:
+		c.define(stmt.Name, Any)
+	case *ast.LetStmt:
+		t := c.initializer(stmt.Type, stmt.Value, "let "+stmt.Pattern.String())
+		c.destructure(stmt.Pattern, t, true)
+	case *ast.ConstStmt:
+		t := c.initializer(stmt.Type, stmt.Value, "const "+stmt.Name.Value)
+		c.define(stmt.Name, t)
+	case *ast.ReturnStmt:
+		c.returnStmt(stmt)
+	case *ast.BreakStmt:
+		if stmt.Value != nil {
+			c.expr(stmt.Value, nil)
+		}
+	}
+}
+
+// initializer checks the value of a declaration with the optional
+// annotation typ and returns the type of the declared value.
+func (c *checker) initializer(typ ast.TypeExpr, value ast.Expr, context string) Type {
+	if typ == nil {
+		return c.expr(value, nil)
+	}
+	t := c.annotation(typ)
+	c.expr(value, &expected{typ: t, context: context})
+	return t
+}
+
+func (c *checker) returnStmt(stmt *ast.ReturnStmt) {
+	switch {
+	case c.fn == nil:
+		if stmt.Value != nil {
+			c.expr(stmt.Value, nil)
+		}
+	case c.fn.result == nil:
+		t := Type(Null)
+		if stmt.Value != nil {
+			t = c.expr(stmt.Value, nil)
+		}
+		c.fn.returns = join(c.fn.returns, t)
+	case stmt.Value == nil:
+		if !AssignableTo(Null, c.fn.result) {
+			c.errorf(stmt.Return, diag.Span("return"), "missing return value in function returning %s", c.fn.result)
+		}
+	default:
+		c.expr(stmt.Value, &expected{typ: c.fn.result, context: "return"})
+	}
+}
+
+// expr returns the type of e. If want is not nil, e must be assignable
+// to want.typ.
+func (c *checker) expr(e ast.Expr, want *expected) Type {
+	t, checked := c.typeOf(e, want)
+	if want != nil && !checked && !AssignableTo(t, want.typ) {
+		d := c.errorf(e.Pos(), diag.Span(e.String()), "cannot use %s (type %s) as %s in %s", e, t, want.typ, want.context)
+		if want.note != nil {
+			d.Notes = append(d.Notes, *want.note)
+		}
+	}
+	return t
+}
+
+// typeOf returns the type of e. It reports whether it has checked e
+// against want itself, which it does for expressions whose value comes
+// from one of their branches.
+func (c *checker) typeOf(e ast.Expr, want *expected) (Type, bool) {
+	switch e := e.(type) {
+	case *ast.IntLit:
+		return Int, false
+	case *ast.StringLit:
+		return Str, false
+	case *ast.BoolLit:
+		return Bool, false
+	case *ast.NullLit:
+		return Null, false
+
+	case *ast.Ident:
+		v, ok := c.vars[e]
+		switch {
+		case ok && v.typ != nil:
+			return v.typ, false
+		case ok:
+			// Declared later, or the function itself inside its body.
+			return Any, false
+		}
+		if t, ok := builtins[e.Value]; ok {
+			return t, false
+		}
+		return Any, false
+
+	case *ast.TupleLit:
+		// The elements of a literal are checked one by one, so that an
+		// error points at the one that does not fit.
+		var wants []*expected
+		if t, ok := want.elemTypes(); ok && len(t.Elems) == len(e.Elems) {
+			for _, elem := range t.Elems {
+				wants = append(wants, &expected{typ: elem, context: want.context})
+			}
+		}
+		elems := make([]Type, len(e.Elems))
+		for i, elem := range e.Elems {
+			var want *expected
+			if wants != nil {
+				want = wants[i]
+			}
+			elems[i] = c.expr(elem, want)
+		}
+		return &Tuple{Elems: elems}, wants != nil
+
+	case *ast.ArrayLit:
+		var elemWant *expected
+		if want != nil {
+			if t, ok := want.typ.(*Array); ok {
+				elemWant = &expected{typ: t.Elem, context: want.context}
+			}
+		}
+		elem := Type(never)
+		for _, x := range e.Elems {
+			elem = join(elem, c.expr(x, elemWant))
+		}
+		if elem == never {
+			elem = Any
+		}
+		return &Array{Elem: elem}, elemWant != nil
+
+	case *ast.PrefixExpr:
+		return c.prefix(e), false
+
+	case *ast.InfixExpr:
+		if e.Op.IsAssignment() {
+			return c.assign(e), false
+		}
+		return c.infix(e), false
+
+	case *ast.SelectorExpr:
+		return c.selector(e), false
+
+	case *ast.StructLit:
+		return c.structLit(e), false
+
+	case *ast.CallExpr:
+		return c.call(e), false
+
+	case *ast.BlockExpr:
+		return c.block(e, want)
+
+	case *ast.IfExpr:
+		c.expr(e.Condition, nil)
+		t := c.expr(e.TrueCase, want)
+		if e.FalseCase == nil {
+			if want != nil && !AssignableTo(Null, want.typ) {
+				c.errorf(e.If, diag.Span("if"), "cannot use if without else (type null) as %s in %s", want.typ, want.context)
+			}
+			return join(t, Null), true
+		}
+		return join(t, c.expr(e.FalseCase, want)), true
+
+	case *ast.WhileExpr:
+		c.expr(e.Condition, nil)
+		c.expr(e.Body, nil)
+		return Any, false
+
+	case *ast.ForExpr:
+		var elem Type
+		switch t := c.expr(e.Iter, nil).(type) {
+		case *Array:
+			elem = t.Elem
+		case *Basic:
+			switch t {
+			case Range:
+				elem = Int
+			case Map, Any, never:
+				elem = Any
+			default:
+				c.errorf(e.Iter.Pos(), diag.Span(e.Iter.String()), "cannot iterate over %s (type %s)", e.Iter, t)
+				elem = Any
+			}
+		default:
+			c.errorf(e.Iter.Pos(), diag.Span(e.Iter.String()), "cannot iterate over %s (type %s)", e.Iter, t)
+			elem = Any
+		}
+		c.define(e.Var, elem)
+		c.expr(e.Body, nil)
+		return Any, false
+
+	case *ast.MatchExpr:
+		subject := c.expr(e.Subject, nil)
+		t := Type(never)
+		for _, arm := range e.Arms {
+			c.destructure(arm.Pattern, subject, false)
+			if arm.Guard != nil {
+				c.expr(arm.Guard, nil)
+			}
+			t = join(t, c.expr(arm.Body, want))
+		}
+		// A match no arm of which matches is null, but whether one
+		// always does is not known here, so only the inferred type
+		// accounts for it.
+		return join(t, Null), true
+
+	case *ast.FuncLit:
+		return c.funcLit(e), false
+	}
+	return Any, false
+}
+
+func (c *checker) block(b *ast.BlockExpr, want *expected) (Type, bool) {
+	if len(b.Stmts) == 0 {
+		return Null, false
+	}
+	for _, stmt := range b.Stmts[:len(b.Stmts)-1] {
+		c.stmt(stmt)
+	}
+
+	switch last := b.Stmts[len(b.Stmts)-1].(type) {
+	case *ast.ExprStmt:
+		return c.expr(last.Expr, want), true
+	case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		c.stmt(last)
+		return never, true
+	default:
+		c.stmt(last)
+	}
+
+	if want != nil && !AssignableTo(Null, want.typ) {
+		if want.result {
+			c.errorf(b.Lbrace, 1, "missing return in function returning %s", want.typ)
+		} else {
+			c.errorf(b.Lbrace, 1, "cannot use block without a final value (type null) as %s in %s", want.typ, want.context)
+		}
+	}
+	return Null, true
+}
+
+func (c *checker) prefix(e *ast.PrefixExpr) Type {
+	t := c.expr(e.Right, nil)
+	switch e.Op {
+	case token.SUB, token.TILDE:
+		if t != Int && t != Any && t != never {
+			c.errorf(e.OpPos, diag.Span(e.Op.String()), "invalid operation: %s%s (operator %s not defined on %s)",
+				e.Op, e.Right, e.Op, t)
+		}
+		return Int
+	case token.NOT:
+		return Bool
+	}
+	return Any
+}
+
+func (c *checker) infix(e *ast.InfixExpr) Type {
+	left := c.expr(e.Left, nil)
+	right := c.expr(e.Right, nil)
+
+	switch e.Op {
+	case token.EQ, token.NEQ:
+		return Bool
+	case token.LAND, token.LOR:
+		// With truthiness the result is one of the operands.
+		if left == Bool && right == Bool {
+			return Bool
+		}
+		return Any
+	}
+	return c.binary(e.OpPos, e.Op, left, right)
+}
+
+// binary returns the type of the result of applying the arithmetic,
+// comparison or range operator op to operands of types left and right.
+func (c *checker) binary(pos token.Pos, op token.Token, left, right Type) Type {
+	if left == never || right == never {
+		return never
+	}
+
+	// An operand of unknown type is checked as if it had the type of the
+	// other, since the operators only apply to operands of one type.
+	operand := left
+	if left == Any {
+		operand = right
+	}
+	if operand != Any && left != Any && right != Any && left != right {
+		c.errorf(pos, diag.Span(op.String()), "invalid operation: operator %s not defined on %s and %s", op, left, right)
+		return Any
+	}
+
+	switch op {
+	case token.LT, token.LTE, token.GT, token.GTE:
+		if operand == Int || operand == Str || operand == Any {
+			return Bool
+		}
+	case token.DOTDOT, token.DOTDOT_EQ:
+		if operand == Int || operand == Any {
+			return Range
+		}
+	case token.ADD:
+		if operand == Int || operand == Str {
+			return operand
+		}
+		if operand == Any {
+			return Any
+		}
+	default:
+		if operand == Int || operand == Any {
+			return Int
+		}
+	}
+
+	if left == right {
+		if operand == Int || operand == Str {
+			c.errorf(pos, diag.Span(op.String()), "invalid operation: operator %s not defined on %s", op, operand)
+		} else {
+			c.errorf(pos, diag.Span(op.String()), "invalid operation: operator %s not defined on %s and %s", op, left, right)
+		}
+	} else {
+		c.errorf(pos, diag.Span(op.String()), "invalid operation: operator %s not defined on %s", op, operand)
+	}
+	return Any
+}
+
+func (c *checker) assign(e *ast.InfixExpr) Type {
+	var want *expected
+	switch left := e.Left.(type) {
+	case *ast.Ident:
+		v := c.vars[left]
+		if v != nil && v.Annotation != nil && v.typ != nil {
+			want = &expected{
+				typ:     v.typ,
+				context: "assignment to " + left.Value,
+				note: &diag.Note{
+					Pos: v.Annotation.Pos(),
+					Len: diag.Span(v.Annotation.String()),
+					Msg: fmt.Sprintf("%s is declared as %s here", left.Value, v.typ),
+				},
+			}
+		}
+	case *ast.SelectorExpr:
+		if t := c.selector(left); t != Any {
+			want = &expected{typ: t, context: "assignment to " + left.String()}
+		}
+	default:
+		c.expr(e.Left, nil)
+		return c.expr(e.Right, nil)
+	}
+
+	op, compound := assignOps[e.Op]
+	if !compound {
+		return c.expr(e.Right, want)
+	}
+
+	var left Type = Any
+	if want != nil {
+		left = want.typ
+	} else if name, ok := e.Left.(*ast.Ident); ok {
+		left = c.expr(name, nil)
+	}
+	t := c.binary(e.OpPos, op, left, c.expr(e.Right, nil))
+	if want != nil && !AssignableTo(t, want.typ) {
+		d := c.errorf(e.OpPos, diag.Span(e.Op.String()), "cannot use %s (type %s) as %s in %s", e, t, want.typ, want.context)
+		if want.note != nil {
+			d.Notes = append(d.Notes, *want.note)
+		}
+	}
+	return t
+}
+
+// selector returns the type of the field e selects. Members of modules
+// and values of unknown type are of type any.
+func (c *checker) selector(e *ast.SelectorExpr) Type {
+	switch t := c.expr(e.X, nil).(type) {
+	case *Struct:
+		if field, ok := t.field(e.Sel.Value); ok {
+			return field.Type
+		}
+		c.errorf(e.Sel.Pos(), diag.Span(e.Sel.Value), "%s (type %s) has no field %s", e.X, t, e.Sel.Value)
+	case *Basic:
+		if t != Any && t != never {
+			c.errorf(e.Dot, diag.Span("."), "%s (type %s) has no member %s", e.X, t, e.Sel.Value)
+		}
+	default:
+		c.errorf(e.Dot, diag.Span("."), "%s (type %s) has no member %s", e.X, t, e.Sel.Value)
+	}
+	return Any
+}
+
+// structLit checks the fields of a struct literal against its struct
+// type. Literals of struct types imported from modules are not checked.
+func (c *checker) structLit(e *ast.StructLit) Type {
+	var st *Struct
+	if name, ok := e.Type.(*ast.Ident); ok {
+		if v := c.vars[name]; v != nil {
+			st = v.Struct
+		}
+	}
+	if st == nil {
+		c.expr(e.Type, nil)
+		for _, field := range e.Fields {
+			c.expr(field.Value, nil)
+		}
+		return Any
+	}
+
+	given := make(map[string]bool, len(e.Fields))
+	unknown := false
+	for _, fv := range e.Fields {
+		given[fv.Name.Value] = true
+		field, ok := st.field(fv.Name.Value)
+		if !ok {
+			c.errorf(fv.Name.Pos(), diag.Span(fv.Name.Value), "unknown field %s in %s literal", fv.Name.Value, st)
+			c.expr(fv.Value, nil)
+			unknown = true
+			continue
+		}
+		c.expr(fv.Value, &expected{typ: field.Type, context: fmt.Sprintf("field %s of %s literal", field.Name, st)})
+	}
+	// A misspelled field is usually the missing one, and only the first
+	// error stops compilation, so missing fields are only reported once
+	// every given field is known.
+	for _, field := range st.Fields {
+		if !unknown && !given[field.Name] {
+			c.errorf(e.Lbrace, diag.Span("{"), "missing field %s in %s literal", field.Name, st)
+		}
+	}
+	return st
+}
+
+func (c *checker) call(e *ast.CallExpr) Type {
+	ft := c.expr(e.Func, nil)
+	f, _ := ft.(*Func)
+	name := funcName(e.Func)
+
+	spread := false
+	for i, arg := range e.Args {
+		if s, ok := arg.(*ast.SpreadExpr); ok {
+			spread = true
+			c.expr(s.X, nil)
+			continue
+		}
+		var want *expected
+		if f != nil && !spread && i < len(f.Params) {
+			want = &expected{typ: f.Params[i].Type, context: "argument to " + name}
+		}
+		c.expr(arg, want)
+	}
+	for _, arg := range e.Named {
+		var want *expected
+		if f != nil {
+			for _, param := range f.Params {
+				if param.Name == arg.Name.Value {
+					want = &expected{typ: param.Type, context: "argument to " + name}
+				}
+			}
+		}
+		c.expr(arg.Value, want)
+	}
+
+	switch ft := ft.(type) {
+	case *Func:
+		if !spread && len(e.Named) == 0 {
+			c.arity(e, ft)
+		}
+		return ft.Result
+	case *Basic:
+		if ft == Any || ft == never {
+			return ft
+		}
+	}
+	c.errorf(e.Func.Pos(), diag.Span(e.Func.String()), "cannot call %s (type %s)", e.Func, ft)
+	return Any
+}
+
+// funcName names the function called by fn in errors, the way the
+// evaluator does.
+func funcName(fn ast.Expr) string {
+	if lit, ok := fn.(*ast.FuncLit); ok {
+		return fmt.Sprintf("func literal at %s", lit.Func)
+	}
+	return fn.String()
+}
+
+// arity reports a call passing a number of arguments f does not accept.
+func (c *checker) arity(e *ast.CallExpr, f *Func) {
+	min, max := 0, len(f.Params)
+	for _, param := range f.Params {
+		if !param.Optional {
+			min++
+		}
+	}
+	if f.Variadic {
+		max = -1
+	}
+
+	n := len(e.Args)
+	pos, span, name := e.Func.Pos(), diag.Span(e.Func.String()), funcName(e.Func)
+	switch {
+	case n >= min && (max < 0 || n <= max):
+	case max < 0:
+		c.errorf(pos, span, "wrong number of arguments to %s: want at least %d, got %d", name, min, n)
+	case min < max:
+		c.errorf(pos, span, "wrong number of arguments to %s: want %d to %d, got %d", name, min, max, n)
+	default:
+		c.errorf(pos, span, "wrong number of arguments to %s: want %d, got %d", name, min, n)
+	}
+}
+
+func (c *checker) funcLit(lit *ast.FuncLit) Type {
+	f := &Func{Params: make([]Param, len(lit.Params)), Variadic: lit.Rest != nil}
+	for i, param := range lit.Params {
+		t := Type(Any)
+		if param.Type != nil {
+			t = c.annotation(param.Type)
+		}
+		if param.Default != nil {
+			var want *expected
+			if param.Type != nil {
+				want = &expected{typ: t, context: "default value of " + param.Pattern.String()}
+			}
+			c.expr(param.Default, want)
+		}
+		c.destructure(param.Pattern, t, true)
+
+		f.Params[i] = Param{Type: t, Optional: param.Default != nil}
+		if bind, ok := param.Pattern.(*ast.BindPattern); ok {
+			f.Params[i].Name = bind.Name.Value
+		}
+	}
+	if rest := lit.Rest; rest != nil && rest.Name != nil {
+		c.define(rest.Name, &Array{Elem: Any})
+	}
+
+	outer := c.fn
+	defer func() { c.fn = outer }()
+
+	if lit.Result != nil {
+		f.Result = c.annotation(lit.Result)
+		c.fn = &funcInfo{result: f.Result}
+		c.expr(lit.Body, &expected{typ: f.Result, context: "return", result: true})
+		return f
+	}
+
+	c.fn = &funcInfo{returns: never}
+	body := c.expr(lit.Body, nil)
+	f.Result = join(body, c.fn.returns)
+	if f.Result == never {
+		f.Result = Any
+	}
+	return f
+}
@@ -0,0 +1,237 @@
+package types
+
+import "oasis/ast"
+
+// Var is a variable declared by the program.
+type Var struct {
+	Name string
+	Decl *ast.Ident
+
+	// Annotation is the declared type, or nil.
+	Annotation ast.TypeExpr
+
+	// Assigned reports whether the program assigns to the variable after
+	// declaring it.
+	Assigned bool
+
+	// Struct is the type declared by a struct statement, or nil.
+	Struct *Struct
+
+	// typ is the type of the variable's values, or nil before the
+	// checker reaches its declaration.
+	typ Type
+}
+
+// scope maps names to the variables they denote. decls holds every
+// variable declared in the scope, including those a later declaration
+// of the same name shadows.
+type scope struct {
+	vars  map[string]*Var
+	decls []*Var
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: make(map[string]*Var), outer: outer}
+}
+
+func (s *scope) lookup(name string) *Var {
+	for ; s != nil; s = s.outer {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// resolver maps every identifier that declares or uses a variable or
+// names a struct type to the variable. Function bodies are resolved after
+// the rest of the program, the way the checks in package check scope
+// them, and so are the fields of structs, which may refer to struct types
+// declared after them.
+type resolver struct {
+	uses    map[*ast.Ident]*Var
+	funcs   []deferredFunc
+	structs []deferredStruct
+
+	// closure is the scope the function whose body is being resolved
+	// closes over, or nil outside function bodies.
+	closure *scope
+}
+
+type deferredStruct struct {
+	stmt  *ast.StructStmt
+	scope *scope
+}
+
+type deferredFunc struct {
+	lit   *ast.FuncLit
+	scope *scope
+}
+
+// resolve returns the variables used in prog and the struct declarations
+// in it.
+func resolve(prog *ast.Program) (map[*ast.Ident]*Var, []*ast.StructStmt) {
+	r := &resolver{uses: make(map[*ast.Ident]*Var)}
+	r.walk(prog, newScope(nil))
+
+	var structs []*ast.StructStmt
+	for {
+		for _, st := range r.structs {
+			for _, field := range st.stmt.Fields {
+				if field.Type != nil {
+					r.walk(field.Type, st.scope)
+				}
+			}
+			structs = append(structs, st.stmt)
+		}
+		r.structs = nil
+		if len(r.funcs) == 0 {
+			return r.uses, structs
+		}
+
+		fn := r.funcs[0]
+		r.funcs = r.funcs[1:]
+		r.closure = fn.scope
+
+		for _, param := range fn.lit.Params {
+			if param.Type != nil {
+				r.walk(param.Type, fn.scope)
+			}
+		}
+		if fn.lit.Result != nil {
+			r.walk(fn.lit.Result, fn.scope)
+		}
+
+		s := newScope(fn.scope)
+		for _, param := range fn.lit.Params {
+			r.declarePattern(s, param.Pattern, param.Type)
+		}
+		if rest := fn.lit.Rest; rest != nil && rest.Name != nil {
+			r.declare(s, rest.Name, nil)
+		}
+		for _, param := range fn.lit.Params {
+			if param.Default != nil {
+				r.walk(param.Default, s)
+			}
+		}
+		r.walk(fn.lit.Body, s)
+	}
+}
+
+func (r *resolver) declare(s *scope, name *ast.Ident, annotation ast.TypeExpr) {
+	v := &Var{Name: name.Value, Decl: name, Annotation: annotation}
+	s.vars[name.Value] = v
+	s.decls = append(s.decls, v)
+	r.uses[name] = v
+}
+
+// assign marks the variable name as assigned to. A function may run
+// before or after any of the declarations of a name in the scopes it
+// closes over, so an assignment from its body to such a name marks
+// every variable it might denote.
+func (r *resolver) assign(s *scope, name string) {
+	for ; s != r.closure; s = s.outer {
+		if v, ok := s.vars[name]; ok {
+			v.Assigned = true
+			return
+		}
+	}
+	for ; s != nil; s = s.outer {
+		for _, v := range s.decls {
+			if v.Name == name {
+				v.Assigned = true
+			}
+		}
+	}
+}
+
+// declarePattern declares the names bound by pattern. Only a plain name
+// carries the annotation; the names in a destructuring pattern take
+// their types from the annotated whole.
+func (r *resolver) declarePattern(s *scope, pattern ast.Pattern, annotation ast.TypeExpr) {
+	if _, ok := pattern.(*ast.BindPattern); !ok {
+		annotation = nil
+	}
+	for _, name := range ast.PatternNames(pattern) {
+		r.declare(s, name, annotation)
+	}
+}
+
+func (r *resolver) walk(node ast.Node, s *scope) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			if v := s.lookup(n.Value); v != nil {
+				r.uses[n] = v
+			}
+		case *ast.ImportStmt:
+			r.declare(s, n.Name, nil)
+			return false
+		case *ast.LetStmt:
+			if n.Type != nil {
+				r.walk(n.Type, s)
+			}
+			r.walk(n.Value, s)
+			r.declarePattern(s, n.Pattern, n.Type)
+			return false
+		case *ast.ConstStmt:
+			if n.Type != nil {
+				r.walk(n.Type, s)
+			}
+			r.walk(n.Value, s)
+			r.declare(s, n.Name, n.Type)
+			return false
+		case *ast.StructStmt:
+			r.declare(s, n.Name, nil)
+			r.uses[n.Name].Struct = &Struct{Name: n.Name.Value}
+			r.structs = append(r.structs, deferredStruct{stmt: n, scope: s})
+			return false
+		case *ast.StructLit:
+			r.walk(n.Type, s)
+			for _, field := range n.Fields {
+				r.walk(field.Value, s)
+			}
+			return false
+		case *ast.BlockExpr:
+			inner := newScope(s)
+			for _, stmt := range n.Stmts {
+				r.walk(stmt, inner)
+			}
+			return false
+		case *ast.ForExpr:
+			r.walk(n.Iter, s)
+			inner := newScope(s)
+			r.declare(inner, n.Var, nil)
+			r.walk(n.Body, inner)
+			return false
+		case *ast.MatchArm:
+			inner := newScope(s)
+			r.declarePattern(inner, n.Pattern, nil)
+			if n.Guard != nil {
+				r.walk(n.Guard, inner)
+			}
+			r.walk(n.Body, inner)
+			return false
+		case *ast.FuncLit:
+			r.funcs = append(r.funcs, deferredFunc{lit: n, scope: s})
+			return false
+		case *ast.SelectorExpr:
+			r.walk(n.X, s)
+			return false
+		case *ast.NamedArg:
+			r.walk(n.Value, s)
+			return false
+		case *ast.InfixExpr:
+			if name, ok := n.Left.(*ast.Ident); ok && n.Op.IsAssignment() {
+				r.assign(s, name.Value)
+			}
+		case *ast.NamedType:
+			if v := s.lookup(n.Name.Value); v != nil {
+				r.uses[n.Name] = v
+			}
+			return false
+		}
+		return true
+	})
+}
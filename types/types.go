@@ -0,0 +1,218 @@
+// Package types checks type annotations and infers the types of
+// expressions. Checking is gradual: a value whose type is not known,
+// such as an unannotated parameter, has type any and is accepted
+// everywhere, so only operations that are certain to fail are reported.
+package types
+
+import "bytes"
+
+type Type interface {
+	String() string
+}
+
+// Basic is a type without structure.
+type Basic struct {
+	name string
+}
+
+func (b *Basic) String() string { return b.name }
+
+var (
+	Any   = &Basic{"any"}
+	Int   = &Basic{"int"}
+	Str   = &Basic{"str"}
+	Bool  = &Basic{"bool"}
+	Null  = &Basic{"null"}
+	Range = &Basic{"range"}
+	Map   = &Basic{"map"}
+
+	// never is the type of blocks that return, break or continue instead
+	// of producing a value.
+	never = &Basic{"never"}
+)
+
+// predeclared maps the type names that annotations may use to their
+// types.
+var predeclared = map[string]Type{
+	"any":   Any,
+	"int":   Int,
+	"str":   Str,
+	"bool":  Bool,
+	"null":  Null,
+	"range": Range,
+	"map":   Map,
+}
+
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string { return "[" + a.Elem.String() + "]" }
+
+type Tuple struct {
+	Elems []Type
+}
+
+func (t *Tuple) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	for i, elem := range t.Elems {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(elem.String())
+	}
+	if len(t.Elems) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// Func is the type of a function. Variadic functions accept any number
+// of arguments after Params.
+type Func struct {
+	Params   []Param
+	Variadic bool
+	Result   Type
+}
+
+// Param is a function parameter. Name is empty for parameters that
+// cannot be passed by name.
+type Param struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+func (f *Func) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("func(")
+	for i, param := range f.Params {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(param.Type.String())
+	}
+	out.WriteString(") -> ")
+	out.WriteString(f.Result.String())
+
+	return out.String()
+}
+
+// Struct is a struct type. Struct types are distinct from each other
+// even if their fields are the same.
+type Struct struct {
+	Name   string
+	Fields []Field
+}
+
+// Field is a field of a struct type. Unannotated fields are of type any.
+type Field struct {
+	Name string
+	Type Type
+}
+
+func (s *Struct) String() string { return s.Name }
+
+func (s *Struct) field(name string) (Field, bool) {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// builtins holds the types of the default builtins.
+var builtins = map[string]Type{
+	"len":   &Func{Params: []Param{{Type: Any}}, Result: Int},
+	"type":  &Func{Params: []Param{{Type: Any}}, Result: Str},
+	"str":   &Func{Params: []Param{{Type: Any}}, Result: Str},
+	"help":  &Func{Params: []Param{{Type: Any}}, Result: Str},
+	"print": &Func{Variadic: true, Result: Null},
+}
+
+// AssignableTo reports whether a value of type v can be used where type t
+// is expected.
+func AssignableTo(v, t Type) bool {
+	if v == Any || t == Any || v == never {
+		return true
+	}
+
+	switch t := t.(type) {
+	case *Array:
+		v, ok := v.(*Array)
+		return ok && AssignableTo(v.Elem, t.Elem)
+	case *Tuple:
+		v, ok := v.(*Tuple)
+		if !ok || len(v.Elems) != len(t.Elems) {
+			return false
+		}
+		for i := range t.Elems {
+			if !AssignableTo(v.Elems[i], t.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	case *Func:
+		// v must accept every call that t accepts and return what t
+		// promises.
+		v, ok := v.(*Func)
+		if !ok || !AssignableTo(v.Result, t.Result) {
+			return false
+		}
+		for i, param := range v.Params {
+			if i >= len(t.Params) {
+				if !param.Optional {
+					return false
+				}
+				continue
+			}
+			if !AssignableTo(t.Params[i].Type, param.Type) {
+				return false
+			}
+		}
+		return len(t.Params) <= len(v.Params) || v.Variadic
+	}
+	return v == t
+}
+
+// join returns the type of a value that is either of type a or of type b.
+func join(a, b Type) Type {
+	switch {
+	case a == never:
+		return b
+	case b == never:
+		return a
+	case AssignableTo(a, b) && AssignableTo(b, a) && !hasAny(a) && !hasAny(b):
+		return a
+	}
+	return Any
+}
+
+// hasAny reports whether t is any or is built from it.
+func hasAny(t Type) bool {
+	switch t := t.(type) {
+	case *Array:
+		return hasAny(t.Elem)
+	case *Tuple:
+		for _, elem := range t.Elems {
+			if hasAny(elem) {
+				return true
+			}
+		}
+		return false
+	case *Func:
+		for _, param := range t.Params {
+			if hasAny(param.Type) {
+				return true
+			}
+		}
+		return hasAny(t.Result)
+	}
+	return t == Any
+}
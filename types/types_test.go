@@ -0,0 +1,132 @@
+package types
+
+import (
+	"oasis/lexer"
+	"oasis/parser"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input string
+		errs  []string
+	}{
+		// Annotations.
+		{"let x: int = 1", nil},
+		{"let x: int = \"a\"", []string{`1:14: cannot use "a" (type str) as int in let x`}},
+		{"let x: str = 1 + 2", []string{"1:14: cannot use (1 + 2) (type int) as str in let x"}},
+		{"let x: any = 1\nlet y: bool = x", nil},
+		{"const n: int = true", []string{"1:16: cannot use true (type bool) as int in const n"}},
+		{"let x: num = 1", []string{"1:8: unknown type num"}},
+		{"let xs: [int] = [1, 2]", nil},
+		{"let xs: [int] = [1, \"a\"]", []string{`1:21: cannot use "a" (type str) as int in let xs`}},
+		{"let xs: [str] = [1]", []string{"1:18: cannot use 1 (type int) as str in let xs"}},
+		{"let xs: [int] = (1,)", []string{"1:17: cannot use (1,) (type (int,)) as [int] in let xs"}},
+		{"let xs: [int] = []", nil},
+		{"let p: (int, str) = (1, \"a\")", nil},
+		{"let p: (int, str) = (1, 2)", []string{"1:25: cannot use 2 (type int) as str in let p"}},
+		{"let p: (int, str) = (1, 2, 3)", []string{"1:21: cannot use (1, 2, 3) (type (int, int, int)) as (int, str) in let p"}},
+		{"let n: null = null", nil},
+
+		// Inference through unannotated bindings.
+		{"let a = 1\nlet b: str = a", []string{"2:14: cannot use a (type int) as str in let b"}},
+		{"let mut a = 1\na = \"s\"\nlet b: str = a", nil},
+		{"let (a, b) = (1, \"s\")\nlet c: int = b", []string{"2:14: cannot use b (type str) as int in let c"}},
+		{"let [a, ...rest] = [1, 2]\nlet b: [int] = rest\nlet c: str = a", []string{"3:14: cannot use a (type int) as str in let c"}},
+		{"let (a, b) = 1", []string{"1:5: cannot destructure type int with tuple pattern (a, b)"}},
+		{"let (a, b) = (1, 2, 3)", []string{"1:5: tuple pattern (a, b) needs 2 elements, got 3"}},
+		{"let [a] = (1,)", []string{"1:5: cannot destructure type (int,) with array pattern [a]"}},
+		{"for i in 0..3 { let s: str = i }", []string{"1:30: cannot use i (type int) as str in let s"}},
+		{"for x in 5 {}", []string{"1:10: cannot iterate over 5 (type int)"}},
+		{"match 1 { (a, b) => a, n => n }", nil},
+
+		// Operators.
+		{"1 + \"a\"", []string{"1:3: invalid operation: operator + not defined on int and str"}},
+		{"\"a\" - \"b\"", []string{"1:5: invalid operation: operator - not defined on str"}},
+		{"true < false", []string{"1:6: invalid operation: operator < not defined on bool and bool"}},
+		{"let f = func(x) { x + true }", []string{"1:21: invalid operation: operator + not defined on bool"}},
+		{"let f = func(x) { x + 1 }", nil},
+		{"-\"a\"", []string{`1:1: invalid operation: -"a" (operator - not defined on str)`}},
+		{"let s: str = \"a\" + \"b\"\nlet b: bool = 1 < 2\nlet r: range = 0..10", nil},
+		{"let b: bool = 1 == \"a\"", nil},
+		{"let x: int = true && false", []string{"1:14: cannot use (true && false) (type bool) as int in let x"}},
+
+		// Assignments.
+		{"let mut x: int = 1\nx = 2\nx += 3", nil},
+		{"let mut x: int = 1\nx = \"a\"", []string{`2:5: cannot use "a" (type str) as int in assignment to x`}},
+		{"let mut x: str = \"a\"\nx += 1", []string{"2:3: invalid operation: operator + not defined on str and int"}},
+		{"let f = func(n: int) { n = null }", []string{"1:28: cannot use null (type null) as int in assignment to n"}},
+		{"let x = 1\nlet f = func() { x = \"s\" }\nf()\nlet y: str = x\nlet x = 2", nil},
+		{"let x = 1\n{ let f = func() { x = \"s\" }\nf()\nlet y: str = x\nlet x = 2 }", nil},
+		{"let x = 1\nlet f = func() { let x = \"s\"\nx = \"t\" }\nlet y: str = x", []string{"4:14: cannot use x (type int) as str in let y"}},
+
+		// Branches.
+		{"let x: int = if c { 1 } else { 2 }", nil},
+		{"let x: int = if c { 1 } else { \"a\" }", []string{`1:32: cannot use "a" (type str) as int in let x`}},
+		{"let x: int = if c { 1 }", []string{"1:14: cannot use if without else (type null) as int in let x"}},
+		{"let x: int = { let y = 1 }", []string{"1:14: cannot use block without a final value (type null) as int in let x"}},
+		{"let x: str = match y { 1 => \"a\", _ => 2 }", []string{"1:39: cannot use 2 (type int) as str in let x"}},
+		{"let x = if c { 1 } else { 2 }\nlet y: str = x", []string{"2:14: cannot use x (type int) as str in let y"}},
+		{"let x = if c { 1 } else { \"a\" }\nlet y: bool = x", nil},
+
+		// Functions and calls.
+		{"let f = func(a: int, b: str) -> bool { a > 0 }", nil},
+		{"let f = func(a: int) -> str { a }", []string{"1:31: cannot use a (type int) as str in return"}},
+		{"let f = func(a: int) -> str { if a > 0 { return a }\n\"b\" }", []string{"1:49: cannot use a (type int) as str in return"}},
+		{"let f = func(a: int) -> int { return }", []string{"1:31: missing return value in function returning int"}},
+		{"let f = func(a: int) -> int { let b = a }", []string{"1:29: missing return in function returning int"}},
+		{"let f = func() -> null { print(1) }", nil},
+		{"let f = func(a: int) -> int { return a }", nil},
+		{"let f = func(a: int, b: str) { a }\nf(1, 2)", []string{"2:6: cannot use 2 (type int) as str in argument to f"}},
+		{"let f = func(a: int, b: str) { a }\nf(b: 1, a: 2)", []string{"2:6: cannot use 1 (type int) as str in argument to f"}},
+		{"let f = func(a: int = \"x\") { a }", []string{`1:23: cannot use "x" (type str) as int in default value of a`}},
+		{"let f = func(a, b = 1) { a }\nf()\nf(1, 2, 3)", []string{"2:1: wrong number of arguments to f: want 1 to 2, got 0", "3:1: wrong number of arguments to f: want 1 to 2, got 3"}},
+		{"let f = func(a, ...rest) { a }\nf(1, 2, 3)\nf(...xs)", nil},
+		{"let f = func() { 1 }\nlet s: str = f()", []string{"2:14: cannot use f() (type int) as str in let s"}},
+		{"let f = func(n) { if n > 0 { return \"a\" }\n\"b\" }\nlet x: int = f(1)", []string{"3:14: cannot use f(1, ) (type str) as int in let x"}},
+		{"let f = func(n) { if n > 0 { return 1 }\n\"b\" }\nlet x: bool = f(1)", nil},
+		{"let g: func(int) -> int = func(n: int) -> int { n }", nil},
+		{"let g: func(int) -> int = func(s: str) -> int { 1 }", []string{"1:27: cannot use func(s: str, ) -> int { 1; } (type func(str) -> int) as func(int) -> int in let g"}},
+		{"let n: str = len(\"abc\")", []string{`1:14: cannot use len("abc", ) (type int) as str in let n`}},
+		{"func(a) { a }(1, 2)", []string{"1:1: wrong number of arguments to func literal at 1:1: want 1, got 2"}},
+		{"len(1, 2)", []string{"1:1: wrong number of arguments to len: want 1, got 2"}},
+		{"let a = 1\na(2)", []string{"2:1: cannot call a (type int)"}},
+		{"let fib = func(n: int) -> int { if n < 2 { n } else { fib(n - 1) + fib(n - 2) } }", nil},
+		{"let f = func() { g(1) }\nlet g = func(s: str) { s }", nil},
+
+		// Structs.
+		{"struct P { x: int, y }\nlet p = P { x: 1, y: \"a\" }\nlet x: int = p.x\nlet y: bool = p.y", nil},
+		{"struct P { x: int }\nlet p: P = P { x: \"a\" }", []string{`2:19: cannot use "a" (type str) as int in field x of P literal`}},
+		{"struct P { x: int, y: int }\nP { x: 1, z: 2 }", []string{"2:11: unknown field z in P literal"}},
+		{"struct P { x: int, y: int }\nP { x: 1 }", []string{"2:3: missing field y in P literal"}},
+		{"struct P { x: int }\nlet p = P { x: 1 }\nlet s: str = p.x", []string{"3:14: cannot use p.x (type int) as str in let s"}},
+		{"struct P { x: int }\nlet p = P { x: 1 }\np.y", []string{"3:3: p (type P) has no field y"}},
+		{"struct P { x: int }\nlet p = P { x: 1 }\np.x = \"a\"\np.x += 1", []string{`3:7: cannot use "a" (type str) as int in assignment to p.x`}},
+		{"struct P { x: str }\nlet p = P { x: \"a\" }\np.x += 1", []string{"3:5: invalid operation: operator + not defined on str and int"}},
+		{"struct A { b: B }\nstruct B { n: int }\nlet a = A { b: B { n: 1 } }\nlet s: str = a.b.n", []string{"4:14: cannot use a.b.n (type int) as str in let s"}},
+		{"struct P { x: int }\nstruct Q { x: int }\nlet p: P = Q { x: 1 }", []string{"3:12: cannot use (Q { x: 1 }) (type Q) as P in let p"}},
+		{"let n = 1\nlet x: n = 1", []string{"2:8: n is not a type"}},
+		{"let f = func(p: P) -> int { p.x }\nstruct P { x: int }", nil},
+		{"let xs = [1]\nxs.len", []string{"2:3: xs (type [int]) has no member len"}},
+		{"import \"m\"\nlet p = m.P { x: 1 }\nm.f", nil},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("tests[%d]: %s", i, p.Error())
+		}
+
+		diags := Check(program)
+		if len(diags) != len(tt.errs) {
+			t.Errorf("tests[%d]: expected %d errors, got %d: %v", i, len(tt.errs), len(diags), diags)
+			continue
+		}
+		for j, d := range diags {
+			if d.Error() != tt.errs[j] {
+				t.Errorf("tests[%d]: expected %q, got %q", i, tt.errs[j], d)
+			}
+		}
+	}
+}